@@ -0,0 +1,70 @@
+package hosts
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lookupCache is a LRU cache of the results of the lookups, including the hosts not found.
+// The results are bound to the version of the host table they are looked up in,
+// so that they are invalidated when the table is replaced.
+type lookupCache struct {
+	size  int
+	table *table // the version of the host table of the cached results
+	items map[string]*list.Element
+	lru   *list.List // of *cacheItem, the most recently used first
+	mux   sync.Mutex
+}
+
+type cacheItem struct {
+	host    string
+	indexes []int
+}
+
+func newLookupCache(size int) *lookupCache {
+	return &lookupCache{
+		size:  size,
+		items: make(map[string]*list.Element, size),
+		lru:   list.New(),
+	}
+}
+
+// get returns the cached indexes of the hosts of t matching host.
+func (c *lookupCache) get(t *table, host string) ([]int, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.table != t {
+		return nil, false
+	}
+	e, ok := c.items[host]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheItem).indexes, true
+}
+
+// add caches the indexes of the hosts of t matching host, evicting the least recently used result if the cache is full.
+// The cached results of the other versions of the host table are dropped.
+func (c *lookupCache) add(t *table, host string, indexes []int) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.table != t {
+		c.table = t
+		c.items = make(map[string]*list.Element, c.size)
+		c.lru.Init()
+	}
+	if e, ok := c.items[host]; ok {
+		e.Value.(*cacheItem).indexes = indexes
+		c.lru.MoveToFront(e)
+		return
+	}
+	if c.lru.Len() >= c.size {
+		e := c.lru.Back()
+		delete(c.items, e.Value.(*cacheItem).host)
+		c.lru.Remove(e)
+	}
+	c.items[host] = c.lru.PushFront(&cacheItem{host: host, indexes: indexes})
+}
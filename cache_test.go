@@ -0,0 +1,74 @@
+package hosts
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLookupCache(t *testing.T) {
+	c := newLookupCache(2)
+	t1, t2 := &table{}, &table{}
+
+	c.add(t1, "a", []int{0})
+	c.add(t1, "b", nil)
+	if v, ok := c.get(t1, "a"); !ok || len(v) != 1 || v[0] != 0 {
+		t.Errorf("a should be cached as [0], got %v, %v", v, ok)
+	}
+	c.add(t1, "c", []int{1})
+	if _, ok := c.get(t1, "b"); ok {
+		t.Error("b should be evicted")
+	}
+	if _, ok := c.get(t1, "a"); !ok {
+		t.Error("a should be cached")
+	}
+	if _, ok := c.get(t2, "a"); ok {
+		t.Error("a should not be cached for another table")
+	}
+	c.add(t2, "b", nil)
+	if _, ok := c.get(t1, "c"); ok {
+		t.Error("c should be invalidated")
+	}
+	if v, ok := c.get(t2, "b"); !ok || v != nil {
+		t.Errorf("b should be cached as not found, got %v, %v", v, ok)
+	}
+}
+
+func TestHostsLookupCache(t *testing.T) {
+	var matches int32
+	m := MatcherFunc(func(entry Host, query string) bool {
+		atomic.AddInt32(&matches, 1)
+		return entry.Hostname == query
+	})
+	hosts := NewHosts(WithMatcher(m), WithLookupCache(16)).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("192.168.1.1 example.com\n192.168.1.2 example.org")); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+			t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+		}
+		if ip := hosts.Lookup("example.net"); ip != nil {
+			t.Errorf("lookup should be nil, got %s", ip)
+		}
+	}
+	if n := atomic.LoadInt32(&matches); n != 4 {
+		t.Errorf("matches should be 4, got %d", n)
+	}
+	if s := hosts.Stats(); s.Lookups != 6 || s.Hits != 3 {
+		t.Errorf("stats should be 6 lookups and 3 hits, got %+v", s)
+	}
+
+	hosts.Add(NewHost(net.IPv4(192, 168, 1, 3), "example.net"))
+	if ip := hosts.Lookup("example.net"); !ip.Equal(net.IPv4(192, 168, 1, 3)) {
+		t.Errorf("lookup should be %s after add, got %s", net.IPv4(192, 168, 1, 3), ip)
+	}
+	if err := hosts.Reload(strings.NewReader("192.168.2.1 example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 2, 1)) {
+		t.Errorf("lookup should be %s after reload, got %s", net.IPv4(192, 168, 2, 1), ip)
+	}
+}
@@ -0,0 +1,60 @@
+package hosts
+
+import (
+	"net"
+	"sort"
+)
+
+// netHost is a static mapping from a network to names.
+type netHost struct {
+	Net   *net.IPNet
+	Names []string
+}
+
+// ReverseLookupNet returns the names of the networks containing the IP address ip,
+// the names of the more specific networks come first.
+// The networks are the entries of the CIDR notations, such as "10.0.0.0/8 corp",
+// the entries of the addresses are looked up by ReverseLookup.
+func (h *staticHosts) ReverseLookupNet(ip net.IP) (names []string) {
+	if h == nil || ip == nil {
+		return
+	}
+
+	for _, v := range h.load().nets {
+		if !v.Net.Contains(ip) {
+			continue
+		}
+		for _, name := range v.Names {
+			names = appendName(names, name)
+		}
+	}
+	return
+}
+
+// sortNets sorts nets from the most specific to the least specific,
+// the networks with the same prefix length are kept in order.
+func sortNets(nets []netHost) {
+	sort.SliceStable(nets, func(i, j int) bool {
+		a, _ := nets[i].Net.Mask.Size()
+		b, _ := nets[j].Net.Mask.Size()
+		return a > b
+	})
+}
+
+// cloneNets returns a deep copy of nets.
+func cloneNets(nets []netHost) []netHost {
+	if nets == nil {
+		return nil
+	}
+	v := make([]netHost, 0, len(nets))
+	for _, n := range nets {
+		v = append(v, netHost{
+			Net: &net.IPNet{
+				IP:   append(net.IP(nil), n.Net.IP...),
+				Mask: append(net.IPMask(nil), n.Net.Mask...),
+			},
+			Names: append([]string(nil), n.Names...),
+		})
+	}
+	return v
+}
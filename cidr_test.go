@@ -0,0 +1,69 @@
+package hosts
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+var hostsReverseLookupNetTests = []struct {
+	ip    net.IP
+	names []string
+}{
+	{net.IPv4(192, 168, 1, 1), nil},
+	{net.IPv4(10, 1, 2, 3), []string{"corp", "internal"}},
+	{net.IPv4(10, 2, 0, 1), []string{"office", "corp", "internal"}},
+	{net.ParseIP("::ffff:10.2.0.1"), []string{"office", "corp", "internal"}},
+	{net.ParseIP("2001:db8::1"), []string{"v6"}},
+	{net.ParseIP("2001:db9::1"), nil},
+}
+
+func TestHostsReverseLookupNet(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	config := "10.0.0.0/8 corp internal\n" +
+		"10.2.0.0/16 office\n" +
+		"2001:db8::/32 v6\n" +
+		"10.1.2.3 host.corp\n"
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := hosts.Errors(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	for i, tc := range hostsReverseLookupNetTests {
+		names := hosts.ReverseLookupNet(tc.ip)
+		if strings.Join(names, ",") != strings.Join(tc.names, ",") {
+			t.Errorf("#%d test failed: reverse lookup should be %v, got %v", i, tc.names, names)
+		}
+	}
+
+	if names := hosts.ReverseLookup(net.IPv4(10, 1, 2, 3)); strings.Join(names, ",") != "host.corp" {
+		t.Errorf("reverse lookup should be %v, got %v", []string{"host.corp"}, names)
+	}
+	if ip := hosts.Lookup("corp"); ip != nil {
+		t.Errorf("lookup should be nil, got %s", ip)
+	}
+
+	expected := "10.1.2.3 host.corp\n" +
+		"2001:db8::/32 v6\n" +
+		"10.2.0.0/16 office\n" +
+		"10.0.0.0/8 corp internal\n"
+	if s := hosts.String(); s != expected {
+		t.Errorf("output should be %q, got %q", expected, s)
+	}
+}
+
+func TestHostsReloadNetErrors(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("10.0.0.0/8\n10.0.0.0/33 corp\n10.0.0.0/8 -münchen.de")); err != nil {
+		t.Fatal(err)
+	}
+	errs := hosts.Errors()
+	if len(errs) != 3 {
+		t.Fatalf("errors should be 3, got %v", errs)
+	}
+	if names := hosts.ReverseLookupNet(net.IPv4(10, 0, 0, 1)); names != nil {
+		t.Errorf("reverse lookup should be nil, got %v", names)
+	}
+}
@@ -0,0 +1,100 @@
+package hosts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrCNAMECycle is reported for a cname option which makes an alias resolve to itself.
+var ErrCNAMECycle = errors.New("cname cycle")
+
+// maxCNAMEHops is the maximum number of aliases followed to resolve a name.
+const maxCNAMEHops = 8
+
+// cname is an alias of another hostname, defined by the cname option.
+type cname struct {
+	name   string
+	target string
+}
+
+// parseCNAME parses the fields of the option "cname <hostname> <aliases...>",
+// which makes each alias resolve to the entries of hostname.
+func (p *parser) parseCNAME(ss []string) error {
+	if len(ss) < 2 {
+		return ErrTooFewFields
+	}
+	for _, name := range ss {
+		if isPattern(name) || strings.HasPrefix(name, "*.") {
+			return fmt.Errorf("%w %q: cname of pattern", ErrInvalidName, name)
+		}
+	}
+	if err := p.checkNames(ss); err != nil {
+		return err
+	}
+
+	target := ss[0]
+	for _, name := range ss[1:] {
+		if p.cnameCycle(name, target) {
+			return fmt.Errorf("%w: %s -> %s", ErrCNAMECycle, name, target)
+		}
+	}
+	if p.cnames == nil {
+		p.cnames = make(map[string]cname)
+	}
+	for _, name := range ss[1:] {
+		p.cnames[p.key(name)] = cname{name: name, target: target}
+	}
+	return nil
+}
+
+// cnameCycle checks whether the chain of aliases from target leads back to name.
+func (p *parser) cnameCycle(name, target string) bool {
+	k := p.key(name)
+	for t := p.key(target); ; {
+		if t == k {
+			return true
+		}
+		c, ok := p.cnames[t]
+		if !ok {
+			return false
+		}
+		t = p.key(c.target)
+	}
+}
+
+// lookupCNAME returns the indexes of the entries of the hostname host is an alias of,
+// following at most maxCNAMEHops aliases.
+func (h *staticHosts) lookupCNAME(t *table, host string) []int {
+	host = trimPort(host)
+	for i := 0; i < maxCNAMEHops; i++ {
+		c, ok := t.cnames[h.key(host)]
+		if !ok {
+			return nil
+		}
+		if v := h.matchIndexes(t, c.target); len(v) > 0 {
+			return v
+		}
+		host = c.target
+	}
+	return nil
+}
+
+// writeCNAMEs writes the cname options of cnames to b with the fields separated by fs, sorted by the aliases.
+func writeCNAMEs(b *bytes.Buffer, cnames map[string]cname, fs byte) {
+	keys := make([]string, 0, len(cnames))
+	for k := range cnames {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString("cname")
+		b.WriteByte(fs)
+		b.WriteString(cnames[k].target)
+		b.WriteByte(fs)
+		b.WriteString(cnames[k].name)
+		b.WriteByte('\n')
+	}
+}
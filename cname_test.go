@@ -0,0 +1,118 @@
+package hosts
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+var hostsCNAMETests = []struct {
+	host string
+	ip   net.IP
+}{
+	{"example.com", net.IPv4(192, 168, 1, 1)},
+	{"www.example.com", net.IPv4(192, 168, 1, 1)},
+	{"WEB", net.IPv4(192, 168, 1, 1)},
+	{"web:80", net.IPv4(192, 168, 1, 1)},
+	{"cdn.example.com", net.IPv4(192, 168, 1, 1)},
+	{"api.example.com", net.IPv4(192, 168, 1, 2)},
+	{"static.example.net", net.IPv4(192, 168, 1, 3)},
+	{"missing.example.com", nil},
+	{"a", nil},
+	{"h8", net.IPv4(192, 168, 1, 4)},
+	{"h9", nil},
+}
+
+func TestHostsCNAME(t *testing.T) {
+	config := `
+192.168.1.1 example.com
+cname example.com www.example.com web
+cname www.example.com cdn.example.com
+192.168.1.2 api.example.com
+cname example.com api.example.com
+192.168.1.3 *.example.net
+cname static.example.net assets
+cname missing.example.com missing
+cname b a
+192.168.1.4 h0
+cname h0 h1
+cname h1 h2
+cname h2 h3
+cname h3 h4
+cname h4 h5
+cname h5 h6
+cname h6 h7
+cname h7 h8
+cname h8 h9
+cname h9 h10
+`
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := hosts.Errors(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	for i, tc := range hostsCNAMETests {
+		if ip := hosts.Lookup(tc.host); !ip.Equal(tc.ip) {
+			t.Errorf("#%d test failed: lookup of %s should be %s, got %s", i, tc.host, tc.ip, ip)
+		}
+	}
+	if v := hosts.Canonical("cdn.example.com"); v != "example.com" {
+		t.Errorf("canonical should be example.com, got %q", v)
+	}
+
+	// the aliases survive the changes of the entries.
+	hosts.Set(NewHost(net.IPv4(192, 168, 1, 5), "example.com"))
+	if ip := hosts.Lookup("web"); !ip.Equal(net.IPv4(192, 168, 1, 5)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 5), ip)
+	}
+
+	var b bytes.Buffer
+	if _, err := hosts.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	other := NewHosts().(*staticHosts)
+	if err := other.Reload(&b); err != nil {
+		t.Fatal(err)
+	}
+	if ip := other.Lookup("cdn.example.com"); !ip.Equal(net.IPv4(192, 168, 1, 5)) {
+		t.Errorf("lookup should be %s after round trip, got %s", net.IPv4(192, 168, 1, 5), ip)
+	}
+}
+
+var hostsCNAMEErrorsTests = []struct {
+	config string
+	lines  []int
+	err    error
+}{
+	{"cname example.com", []int{1}, ErrTooFewFields},
+	{"cname example.com example.com", []int{1}, ErrCNAMECycle},
+	{"cname a b\ncname b c\ncname c a", []int{3}, ErrCNAMECycle},
+	{"cname a B\ncname b A", []int{2}, ErrCNAMECycle},
+	{"cname *.example.com www", []int{1}, ErrInvalidName},
+	{"cname example.com re:www", []int{1}, ErrInvalidName},
+	{"cname example.com -münchen.de", []int{1}, ErrInvalidName},
+}
+
+func TestHostsCNAMEErrors(t *testing.T) {
+	for i, tc := range hostsCNAMEErrorsTests {
+		hosts := NewHosts().(*staticHosts)
+		if err := hosts.Reload(strings.NewReader(tc.config)); err != nil {
+			t.Fatal(err)
+		}
+		errs := hosts.Errors()
+		if len(errs) != len(tc.lines) {
+			t.Errorf("#%d test failed: errors should be at lines %v, got %v", i, tc.lines, errs)
+			continue
+		}
+		for j := range errs {
+			if errs[j].Line != tc.lines[j] || !errors.Is(errs[j].Err, tc.err) {
+				t.Errorf("#%d test failed: error should be %v at line %d, got %v", i, tc.err, tc.lines[j], &errs[j])
+			}
+		}
+	}
+}
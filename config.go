@@ -0,0 +1,230 @@
+package hosts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is the structured hosts config, in JSON or YAML format:
+//
+//	{
+//		"reload": "30s",
+//		"hosts": [
+//			{"ip": "192.168.1.1", "hostname": "example.com", "aliases": ["example"], "ttl": "1h"}
+//		]
+//	}
+//
+// The config may also be the list of the hosts alone, without the reload period.
+type config struct {
+	Reload string       `json:"reload,omitempty" yaml:"reload,omitempty"`
+	Hosts  []hostConfig `json:"hosts" yaml:"hosts"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, b is either the config object or the list of the hosts.
+func (c *config) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		return json.Unmarshal(b, &c.Hosts)
+	}
+	type object config // without the methods of config
+	return json.Unmarshal(b, (*object)(c))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, value is either the config mapping or the sequence of the hosts.
+func (c *config) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		return value.Decode(&c.Hosts)
+	}
+	type object config // without the methods of config
+	return value.Decode((*object)(c))
+}
+
+// hostConfig is the structured form of Host.
+type hostConfig struct {
+	IP       string            `json:"ip" yaml:"ip"`
+	Hostname string            `json:"hostname" yaml:"hostname"`
+	Aliases  []string          `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	TTL      string            `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Comment  string            `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Weight   int               `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+func newHostConfig(h Host) hostConfig {
+	c := hostConfig{
+		Hostname: h.Hostname,
+		Aliases:  h.Aliases,
+		Comment:  h.Comment,
+		Weight:   h.Weight,
+		Tags:     h.Tags,
+	}
+	if h.IP != nil {
+		c.IP = h.addr()
+	}
+	if h.TTL != 0 {
+		c.TTL = h.TTL.String()
+	}
+	return c
+}
+
+func (c hostConfig) host() (Host, error) {
+	h := Host{
+		Hostname: c.Hostname,
+		Aliases:  c.Aliases,
+		Comment:  c.Comment,
+		Weight:   c.Weight,
+		Tags:     c.Tags,
+	}
+	h.IP, h.Zone = parseIPZone(c.IP)
+	if h.IP == nil && c.IP != "" {
+		return h, ErrInvalidIP
+	}
+	if c.TTL != "" {
+		ttl, err := time.ParseDuration(c.TTL)
+		if err != nil {
+			return h, err
+		}
+		h.TTL = ttl
+	}
+	return h, nil
+}
+
+func (c hostConfig) String() string {
+	return strings.Join(append([]string{c.IP, c.Hostname}, c.Aliases...), " ")
+}
+
+// MarshalJSON implements json.Marshaler, the IP and TTL are encoded as strings.
+func (h Host) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newHostConfig(h))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (h *Host) UnmarshalJSON(b []byte) error {
+	var c hostConfig
+	if err := json.Unmarshal(b, &c); err != nil {
+		return err
+	}
+	v, err := c.host()
+	if err != nil {
+		return err
+	}
+	*h = v
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler, the IP and TTL are encoded as strings.
+func (h Host) MarshalYAML() (interface{}, error) {
+	return newHostConfig(h), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (h *Host) UnmarshalYAML(value *yaml.Node) error {
+	var c hostConfig
+	if err := value.Decode(&c); err != nil {
+		return err
+	}
+	v, err := c.host()
+	if err != nil {
+		return err
+	}
+	*h = v
+	return nil
+}
+
+// ReloadJSON is like Reload, but parses config from r in JSON format, either an object with the reload period and the hosts,
+// or an array of the hosts, such as the output of json.Marshal of []Host.
+// The Line of the ParseError of an invalid entry is its 1-based position in the hosts list.
+func (h *staticHosts) ReloadJSON(r io.Reader) error {
+	if r == nil {
+		return nil
+	}
+	return h.reload(func(p *parser) error {
+		var c config
+		if err := json.NewDecoder(r).Decode(&c); err != nil {
+			return err
+		}
+		return p.parseConfig(&c)
+	})
+}
+
+// ReloadYAML is like Reload, but parses config from r in YAML format, either a mapping with the reload period and the hosts,
+// or a sequence of the hosts, such as the output of yaml.Marshal of []Host.
+// The Line of the ParseError of an invalid entry is its 1-based position in the hosts list.
+func (h *staticHosts) ReloadYAML(r io.Reader) error {
+	if r == nil {
+		return nil
+	}
+	return h.reload(func(p *parser) error {
+		var c config
+		if err := yaml.NewDecoder(r).Decode(&c); err != nil && err != io.EOF {
+			return err
+		}
+		return p.parseConfig(&c)
+	})
+}
+
+// parseConfig parses the structured config c, invalid entries are collected in p.errs.
+// An error is returned if the parsing is aborted.
+func (p *parser) parseConfig(c *config) error {
+	p.hash(c.Reload)
+	if c.Reload != "" {
+		period, err := time.ParseDuration(c.Reload)
+		if err != nil {
+			p.report(ParseError{Text: "reload " + c.Reload, Err: err})
+			if p.err != nil {
+				return p.err
+			}
+		} else {
+			p.period, p.reload = period, true
+		}
+	}
+
+	for i, hc := range c.Hosts {
+		p.line, p.text = i+1, hc.String()
+		p.hash(p.text + " " + hc.TTL + " " + hc.Comment + " " + strconv.Itoa(hc.Weight) + " " + formatTags(hc.Tags, '#'))
+		err := p.parseHostConfig(hc)
+		if err != nil {
+			p.report(ParseError{Line: p.line, Text: p.text, Err: err})
+		}
+		if p.err != nil {
+			return p.err
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseHostConfig(c hostConfig) error {
+	host, err := c.host()
+	if err != nil {
+		return err
+	}
+	return p.addHost(host)
+}
+
+// addHost adds the entry host, which must have an IP, a hostname, a weight which is not negative and valid tags.
+func (p *parser) addHost(host Host) error {
+	p.comment, p.tags = host.Comment, host.Tags
+	if host.IP == nil {
+		return ErrInvalidIP
+	}
+	if host.Hostname == "" {
+		return ErrTooFewFields
+	}
+	if host.Weight < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidWeight, host.Weight)
+	}
+	for key, value := range host.Tags {
+		if err := validateTag(key, value); err != nil {
+			return err
+		}
+	}
+	names := append([]string{host.Hostname}, host.Aliases...)
+	return p.addHosts([]net.IPAddr{{IP: host.IP, Zone: host.Zone}}, []int{host.Weight}, names, host.TTL)
+}
@@ -0,0 +1,187 @@
+package hosts
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestHostsReloadJSON(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+
+	r := `{
+		"reload": "10s",
+		"hosts": [
+			{"ip": "192.168.1.1", "hostname": "example.com", "aliases": ["example"]},
+			{"ip": "foo", "hostname": "example.org"},
+			{"ip": "192.168.1.2", "hostname": "example.com", "ttl": "1h"},
+			{"ip": "192.168.1.3"}
+		]
+	}`
+	if err := hosts.ReloadJSON(strings.NewReader(r)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}
+	if ips := hosts.LookupAll("example.com"); !equalIPs(ips, expected) {
+		t.Errorf("lookup should be %v, got %v", expected, ips)
+	}
+	if ip := hosts.Lookup("example"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+	if period := hosts.Period(); period != 10*time.Second {
+		t.Errorf("period should be %s, got %s", 10*time.Second, period)
+	}
+	if all := hosts.GetAll(); len(all) != 2 || all[1].TTL != time.Hour {
+		t.Errorf("entries should be 2 with the TTL of the second one 1h, got %v", all)
+	}
+	errs := hosts.Errors()
+	if len(errs) != 2 || errs[0].Line != 2 || errs[0].Err != ErrInvalidIP || errs[1].Line != 4 || errs[1].Err != ErrTooFewFields {
+		t.Errorf("errors should be at entry 2 and 4, got %v", errs)
+	}
+
+	r = `[
+		{"ip": "192.168.1.1", "hostname": "example.com", "weight": -1},
+		{"ip": "192.168.1.2", "hostname": "example.org", "tags": {"a b": "c"}},
+		{"ip": "192.168.1.3", "hostname": "example.net", "weight": 2, "tags": {"list": "ads"}}
+	]`
+	invalid := NewHosts().(*staticHosts)
+	if err := invalid.ReloadJSON(strings.NewReader(r)); err != nil {
+		t.Fatal(err)
+	}
+	errs = invalid.Errors()
+	if len(errs) != 2 || errs[0].Line != 1 || !errors.Is(errs[0].Err, ErrInvalidWeight) || errs[1].Line != 2 || !errors.Is(errs[1].Err, ErrInvalidTag) {
+		t.Errorf("errors should be an invalid weight at entry 1 and an invalid tag at entry 2, got %v", errs)
+	}
+	if all := invalid.GetAll(); len(all) != 1 || all[0].Hostname != "example.net" {
+		t.Errorf("entries should be example.net, got %v", all)
+	}
+
+	if err := hosts.ReloadJSON(strings.NewReader("{")); err == nil {
+		t.Error("reload should fail")
+	}
+	if n := hosts.Count(); n != 2 {
+		t.Errorf("count should be %d, got %d", 2, n)
+	}
+}
+
+func TestHostsReloadYAML(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+
+	r := `
+reload: 10s
+hosts:
+- ip: 192.168.1.1
+  hostname: example.com
+  aliases: [example]
+- ip: 2001:db8::1
+  hostname: example.com
+`
+	if err := hosts.ReloadYAML(strings.NewReader(r)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []net.IP{net.IPv4(192, 168, 1, 1), net.ParseIP("2001:db8::1")}
+	if ips := hosts.LookupAll("example.com"); !equalIPs(ips, expected) {
+		t.Errorf("lookup should be %v, got %v", expected, ips)
+	}
+	if period := hosts.Period(); period != 10*time.Second {
+		t.Errorf("period should be %s, got %s", 10*time.Second, period)
+	}
+
+	if err := hosts.ReloadYAML(strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	if n := hosts.Count(); n != 0 {
+		t.Errorf("count should be %d, got %d", 0, n)
+	}
+	if err := hosts.ReloadYAML(strings.NewReader("hosts: {")); err == nil {
+		t.Error("reload should fail")
+	}
+}
+
+func TestHostsReloadList(t *testing.T) {
+	hosts := []Host{
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"),
+		NewHost(net.ParseIP("2001:db8::1"), "example.com"),
+	}
+	b, err := json.Marshal(hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := yaml.Marshal(hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		reload func(h *staticHosts) error
+	}{
+		{"json", func(h *staticHosts) error { return h.ReloadJSON(strings.NewReader(string(b))) }},
+		{"json with spaces", func(h *staticHosts) error { return h.ReloadJSON(strings.NewReader(" \n" + string(b))) }},
+		{"yaml", func(h *staticHosts) error { return h.ReloadYAML(strings.NewReader(string(y))) }},
+		{"yaml object", func(h *staticHosts) error {
+			return h.ReloadYAML(strings.NewReader("hosts:\n" + strings.Replace("\n"+string(y), "\n", "\n  ", -1)))
+		}},
+	}
+	expected := []net.IP{net.IPv4(192, 168, 1, 1), net.ParseIP("2001:db8::1")}
+	for i, tc := range tests {
+		h := NewHosts().(*staticHosts)
+		if err := tc.reload(h); err != nil {
+			t.Errorf("#%d test failed: reload of %s should succeed, got %v", i, tc.name, err)
+			continue
+		}
+		if ips := h.LookupAll("example.com"); !equalIPs(ips, expected) {
+			t.Errorf("#%d test failed: lookup of %s should be %v, got %v", i, tc.name, expected, ips)
+		}
+		if ip := h.Lookup("example"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+			t.Errorf("#%d test failed: lookup of %s should be %s, got %s", i, tc.name, net.IPv4(192, 168, 1, 1), ip)
+		}
+	}
+
+	h := NewHosts().(*staticHosts)
+	if err := h.ReloadJSON(strings.NewReader(`[{"ip": "foo", "hostname": "example.org"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := h.Errors(); len(errs) != 1 || errs[0].Line != 1 || errs[0].Err != ErrInvalidIP {
+		t.Errorf("errors should be at entry 1, got %v", errs)
+	}
+}
+
+func TestHostMarshal(t *testing.T) {
+	host := Host{IP: net.IPv4(192, 168, 1, 1), Hostname: "example.com", Aliases: []string{"example"}, TTL: time.Minute}
+
+	b, err := json.Marshal(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, expected := string(b), `{"ip":"192.168.1.1","hostname":"example.com","aliases":["example"],"ttl":"1m0s"}`; s != expected {
+		t.Errorf("json should be %s, got %s", expected, s)
+	}
+	var v Host
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if !v.IP.Equal(host.IP) || v.Hostname != host.Hostname || len(v.Aliases) != 1 || v.TTL != host.TTL {
+		t.Errorf("host should be %v, got %v", host, v)
+	}
+	if err := json.Unmarshal([]byte(`{"ip":"foo"}`), &v); err != ErrInvalidIP {
+		t.Errorf("error should be %v, got %v", ErrInvalidIP, err)
+	}
+
+	b, err = yaml.Marshal(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v = Host{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if !v.IP.Equal(host.IP) || v.Hostname != host.Hostname || len(v.Aliases) != 1 || v.TTL != host.TTL {
+		t.Errorf("host should be %v, got %v", host, v)
+	}
+}
@@ -0,0 +1,244 @@
+package hosts
+
+import (
+	"fmt"
+	"net"
+)
+
+// ConflictStrategy is the way to resolve a name (hostname or alias) defined on more than one line
+// for the same address family, or defined as a hostname on one line and as an alias on another.
+// A name defined for IPv4 and IPv6 on separate lines, such as localhost of /etc/hosts, is no conflict.
+type ConflictStrategy int
+
+const (
+	// ConflictFirstWins keeps all the definitions, the earlier ones take precedence. It is the default.
+	ConflictFirstWins ConflictStrategy = iota
+	// ConflictLastWins overrides the earlier definitions with the later one.
+	// An earlier entry with the name as its hostname is removed along with its aliases,
+	// an earlier entry with the name as an alias only loses the alias.
+	ConflictLastWins
+	// ConflictError fails the reload.
+	ConflictError
+)
+
+// DuplicateError is a name defined more than once.
+type DuplicateError struct {
+	Name string
+	File string // the file of the first definition, if any
+	Line int    // the line of the first definition, zero if it is not parsed from a config
+}
+
+func (e *DuplicateError) Error() string {
+	switch {
+	case e.Line == 0:
+		return fmt.Sprintf("duplicate name %q", e.Name)
+	case e.File != "":
+		return fmt.Sprintf("duplicate name %q, first defined at %s: line %d", e.Name, e.File, e.Line)
+	default:
+		return fmt.Sprintf("duplicate name %q, first defined at line %d", e.Name, e.Line)
+	}
+}
+
+// definition is the line defining a name.
+type definition struct {
+	file    string
+	line    int
+	entries []int // indexes of the hosts parsed from the line
+}
+
+// nameDefs is the lines defining a name, one for each address family,
+// so that a name may be defined for IPv4 and IPv6 on separate lines, such as localhost of /etc/hosts.
+type nameDefs struct {
+	hostname bool           // the name is the hostname of its entries, rather than an alias
+	lines    [2]*definition // the lines of the IPv4 and IPv6 entries, see family
+}
+
+// family returns the index of the address family of ip in nameDefs.lines, 0 for IPv4 and 1 for IPv6.
+func family(ip net.IP) int {
+	if ip.To4() != nil {
+		return 0
+	}
+	return 1
+}
+
+// define records the names of the hosts p.hosts[start:end] parsed from the current line,
+// and resolves the names already defined on other lines.
+// A name conflicts with an earlier definition if it is defined again for the same address family,
+// or if it is the hostname of one of them and an alias of the other.
+func (p *parser) define(names []string, start, end int) {
+	line := &definition{file: p.file, line: p.line}
+	var families [2]bool
+	for i := start; i < end; i++ {
+		line.entries = append(line.entries, i)
+		families[family(p.hosts[i].IP)] = true
+	}
+
+	for n, name := range names {
+		key := p.key(name)
+		hostname := n == 0
+		nd := p.defs[key]
+		if nd == nil {
+			nd = &nameDefs{hostname: hostname}
+			p.defs[key] = nd
+		}
+
+		// the earlier lines conflicting with the line, by address family.
+		var conflicts [2]*definition
+		for f, def := range nd.lines {
+			if def == nil || def.file == line.file && def.line == line.line {
+				continue // repeated on the same line
+			}
+			if families[f] || nd.hostname != hostname || key == catchAllName {
+				conflicts[f] = def
+			}
+		}
+		for f, def := range conflicts {
+			if def == nil {
+				if families[f] {
+					nd.lines[f] = line
+				}
+				continue
+			}
+
+			dup := ParseError{
+				File: p.file,
+				Line: p.line,
+				Text: p.text,
+				Err:  &DuplicateError{Name: name, File: def.file, Line: def.line},
+			}
+			switch {
+			case key == catchAllName || p.conflict == ConflictLastWins:
+				// only the last catch-all entry is kept regardless of the strategy.
+				p.dups = append(p.dups, dup)
+				p.override(key, def, f)
+				nd.lines[f] = nil
+				if families[f] {
+					nd.lines[f] = line
+				}
+			case p.conflict == ConflictError:
+				p.err = &dup
+				return
+			default:
+				p.dups = append(p.dups, dup)
+			}
+		}
+		if (nd.lines[0] == line || nd.lines[0] == nil) && (nd.lines[1] == line || nd.lines[1] == nil) {
+			nd.hostname = hostname
+		}
+	}
+}
+
+// override removes the name key from the hosts of def of the address family f.
+func (p *parser) override(key string, def *definition, f int) {
+	for _, i := range def.entries {
+		if p.dropped[i] || family(p.hosts[i].IP) != f {
+			continue
+		}
+		host := &p.hosts[i]
+		if p.key(host.Hostname) != key {
+			host.Aliases = removeName(host.Aliases, key, p.key)
+			p.stale = true
+			continue
+		}
+
+		p.dropped[i] = true
+		for _, name := range host.Aliases {
+			name = p.key(name)
+			if nd := p.defs[name]; nd != nil && nd.lines[f] == def {
+				nd.lines[f] = nil
+				if nd.lines[0] == nil && nd.lines[1] == nil {
+					delete(p.defs, name)
+				}
+			}
+		}
+	}
+}
+
+// overrideHosts returns a copy of hosts with the names of host removed where they conflict with host,
+// by the rules of define: the entries with one of the names as their hostname are removed,
+// and the names are removed from the aliases of the other entries.
+func overrideHosts(hosts []Host, host Host, key func(string) string) []Host {
+	names := hostRoles(host, key)
+	f := family(host.IP)
+
+	v := make([]Host, 0, len(hosts)+1)
+	for _, h := range hosts {
+		same := family(h.IP) == f
+		if hostname, ok := names[key(h.Hostname)]; ok && conflicts(key(h.Hostname), same, hostname, true) {
+			continue
+		}
+		var aliases []string
+		for _, alias := range h.Aliases {
+			if hostname, ok := names[key(alias)]; !ok || !conflicts(key(alias), same, hostname, false) {
+				aliases = append(aliases, alias)
+			}
+		}
+		if len(aliases) != len(h.Aliases) {
+			h.Aliases = aliases
+		}
+		v = append(v, h)
+	}
+	return v
+}
+
+// checkDuplicate returns a *DuplicateError if any name of host conflicts with the names of hosts,
+// by the rules of define.
+func checkDuplicate(hosts []Host, host Host, key func(string) string) error {
+	names := hostRoles(host, key)
+	f := family(host.IP)
+
+	for _, h := range hosts {
+		same := family(h.IP) == f
+		if hostname, ok := names[key(h.Hostname)]; ok && conflicts(key(h.Hostname), same, hostname, true) {
+			return &DuplicateError{Name: key(h.Hostname)}
+		}
+		for _, alias := range h.Aliases {
+			if hostname, ok := names[key(alias)]; ok && conflicts(key(alias), same, hostname, false) {
+				return &DuplicateError{Name: key(alias)}
+			}
+		}
+	}
+	return nil
+}
+
+// conflicts reports whether two definitions of the name key conflict, see define:
+// same is whether they are of the same address family, a and b whether the name is the hostname of each.
+func conflicts(key string, same bool, a, b bool) bool {
+	return same || a != b || key == catchAllName
+}
+
+// hostRoles returns the keys of the names of host, each mapped to whether it is the hostname of host.
+func hostRoles(host Host, key func(string) string) map[string]bool {
+	roles := make(map[string]bool, len(host.Aliases)+1)
+	for i, name := range hostNames(host, key) {
+		if _, ok := roles[name]; !ok {
+			roles[name] = i == 0 && host.Hostname != ""
+		}
+	}
+	return roles
+}
+
+// hostNames returns the keys of the hostname and aliases of host.
+func hostNames(host Host, key func(string) string) []string {
+	names := make([]string, 0, len(host.Aliases)+1)
+	if host.Hostname != "" {
+		names = append(names, key(host.Hostname))
+	}
+	for _, alias := range host.Aliases {
+		if alias != "" {
+			names = append(names, key(alias))
+		}
+	}
+	return names
+}
+
+// removeName returns a copy of names without the names with the key k.
+func removeName(names []string, k string, key func(string) string) []string {
+	var v []string
+	for _, name := range names {
+		if key(name) != k {
+			v = append(v, name)
+		}
+	}
+	return v
+}
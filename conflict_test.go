@@ -0,0 +1,113 @@
+package hosts
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+var hostsConflictTests = []struct {
+	strategy ConflictStrategy
+	r        string
+	host     string
+	ips      []net.IP
+	dups     int
+	err      bool
+}{
+	{ConflictFirstWins, "192.168.1.1 example.com\n192.168.1.2 example.com", "example.com", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}, 1, false},
+	{ConflictFirstWins, "example.com 192.168.1.1 192.168.1.2 example.com", "example.com", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}, 0, false},
+	{ConflictFirstWins, "192.168.1.1 example.com example\n192.168.1.2 example", "example", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}, 1, false},
+	{ConflictLastWins, "192.168.1.1 example.com\n192.168.1.2 example.com", "example.com", []net.IP{net.IPv4(192, 168, 1, 2)}, 1, false},
+	{ConflictLastWins, "192.168.1.1 example.com example\n192.168.1.2 example", "example", []net.IP{net.IPv4(192, 168, 1, 2)}, 1, false},
+	{ConflictLastWins, "192.168.1.1 example.com example\n192.168.1.2 example", "example.com", []net.IP{net.IPv4(192, 168, 1, 1)}, 1, false},
+	{ConflictLastWins, "192.168.1.1 example.com example\n192.168.1.2 example.org example.com", "example", nil, 1, false},
+	{ConflictLastWins, "192.168.1.1 example.com example\n192.168.1.2 example.org example.com\n192.168.1.3 example", "example", []net.IP{net.IPv4(192, 168, 1, 3)}, 1, false},
+	{ConflictError, "192.168.1.1 example.com\n192.168.1.2 example.org", "example.com", []net.IP{net.IPv4(192, 168, 1, 1)}, 0, false},
+	{ConflictFirstWins, "0.0.0.0 ads.example.com\n0.0.0.0 ads.example.com\n0.0.0.0 ADS.example.com.", "ads.example.com", []net.IP{net.IPv4zero}, 0, false},
+	{ConflictError, "0.0.0.0 ads.example.com\n0.0.0.0 ads.example.com", "ads.example.com", []net.IP{net.IPv4zero}, 0, false},
+	{ConflictLastWins, "192.168.1.1 example.com\n192.168.1.2 example.com\n192.168.1.1 example.com", "example.com", []net.IP{net.IPv4(192, 168, 1, 1)}, 2, false},
+	{ConflictError, "192.168.1.1 example.com\n192.168.1.2 example.org example.com", "example.com", []net.IP{net.IPv4(10, 0, 0, 1)}, 0, true},
+	{ConflictFirstWins, stockHosts, "localhost", []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}, 0, false},
+	{ConflictLastWins, stockHosts, "localhost", []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}, 0, false},
+	{ConflictError, stockHosts, "localhost", []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}, 0, false},
+	{ConflictLastWins, "example.com 192.168.1.1 ::1\n192.168.1.2 example.com", "example.com", []net.IP{net.IPv6loopback, net.IPv4(192, 168, 1, 2)}, 1, false},
+	{ConflictLastWins, "127.0.0.1 localhost\n::1 ip6-localhost localhost", "localhost", []net.IP{net.IPv6loopback}, 1, false},
+	{ConflictError, "127.0.0.1 localhost\n::1 ip6-localhost localhost", "localhost", nil, 0, true},
+	{ConflictLastWins, "0.0.0.0 *\n:: *", "example.net", []net.IP{net.IPv6zero}, 1, false},
+}
+
+// stockHosts is the loopback entries of a stock /etc/hosts, localhost is defined for IPv4 and IPv6 on separate lines.
+const stockHosts = "127.0.0.1 localhost\n::1 localhost ip6-localhost ip6-loopback"
+
+func TestHostsConflict(t *testing.T) {
+	for i, tc := range hostsConflictTests {
+		hosts := NewHosts(
+			WithInitialHosts(NewHost(net.IPv4(10, 0, 0, 1), "example.com")),
+			WithConflictStrategy(tc.strategy),
+		).(*staticHosts)
+
+		p := hosts.newParser()
+		err := p.parse(strings.NewReader(tc.r))
+		if (err != nil) != tc.err {
+			t.Errorf("#%d test failed: error should be %v, got %v", i, tc.err, err)
+		}
+		if len(p.dups) != tc.dups {
+			t.Errorf("#%d test failed: duplicates should be %d, got %v", i, tc.dups, p.dups)
+		}
+
+		hosts.Reload(strings.NewReader(tc.r))
+		if ips := hosts.LookupAll(tc.host); !equalIPs(ips, tc.ips) {
+			t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+		}
+	}
+}
+
+func TestDuplicateError(t *testing.T) {
+	hosts := NewHosts(WithConflictStrategy(ConflictError)).(*staticHosts)
+
+	err := hosts.Reload(strings.NewReader("192.168.1.1 example.com\n\n192.168.1.2 Example.com"))
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 3 {
+		t.Fatalf("error should be a parse error at line 3, got %v", err)
+	}
+	if s, expected := err.Error(), `line 3: duplicate name "Example.com", first defined at line 1: "192.168.1.2 Example.com"`; s != expected {
+		t.Errorf("error should be %s, got %s", expected, s)
+	}
+}
+
+func TestHostsAddConflict(t *testing.T) {
+	var tests = []struct {
+		strategy ConflictStrategy
+		host     Host
+		lookup   string
+		ips      []net.IP
+		err      bool
+	}{
+		{ConflictFirstWins, NewHost(net.IPv4(192, 168, 1, 2), "example"), "example", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}, false},
+		{ConflictLastWins, NewHost(net.IPv4(192, 168, 1, 2), "example"), "example", []net.IP{net.IPv4(192, 168, 1, 2)}, false},
+		{ConflictLastWins, NewHost(net.IPv4(192, 168, 1, 2), "example"), "example.com", []net.IP{net.IPv4(192, 168, 1, 1)}, false},
+		{ConflictLastWins, NewHost(net.IPv4(192, 168, 1, 2), "example.org", "example.com"), "example", nil, false},
+		{ConflictError, NewHost(net.IPv4(192, 168, 1, 2), "example.org", "EXAMPLE"), "example", []net.IP{net.IPv4(192, 168, 1, 1)}, true},
+		{ConflictError, NewHost(net.IPv4(192, 168, 1, 2), "example.org"), "example.org", []net.IP{net.IPv4(192, 168, 1, 2)}, false},
+		{ConflictError, NewHost(net.IPv6loopback, "example.com"), "example.com", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv6loopback}, false},
+		{ConflictLastWins, NewHost(net.IPv6loopback, "example.com"), "example.com", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv6loopback}, false},
+		{ConflictError, NewHost(net.IPv6loopback, "example.org", "example"), "example", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv6loopback}, false},
+		{ConflictError, NewHost(net.IPv6loopback, "example"), "example", []net.IP{net.IPv4(192, 168, 1, 1)}, true},
+		{ConflictLastWins, NewHost(net.IPv6loopback, "example"), "example", []net.IP{net.IPv6loopback}, false},
+	}
+	for i, tc := range tests {
+		hosts := NewHosts(
+			WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example")),
+			WithConflictStrategy(tc.strategy),
+		).(*staticHosts)
+
+		err := hosts.Add(tc.host)
+		if (err != nil) != tc.err {
+			t.Errorf("#%d test failed: error should be %v, got %v", i, tc.err, err)
+		}
+		if ips := hosts.LookupAll(tc.lookup); !equalIPs(ips, tc.ips) {
+			t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package hosts
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// DialContext connects to addr on the named network, the host of addr is resolved from the host table.
+// If the host is not found, or is an IP address, addr is dialed as is.
+// If the host is found but none of its addresses suits the network, an error is returned.
+// The IPv6 zones of the addresses are used to dial the link-local addresses.
+// It can be used as the DialContext of http.Transport.
+func (h *staticHosts) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	if ip, _ := parseIPZone(host); ip != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	all := h.LookupIPAddr(host)
+	if len(all) == 0 {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	var ips []net.IPAddr
+	for _, ip := range all {
+		if matchNetwork(network, ip.IP) {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: host}
+	}
+
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// matchNetwork checks whether ip can be used on the named network, such as tcp4 or udp6.
+func matchNetwork(network string, ip net.IP) bool {
+	switch {
+	case strings.HasSuffix(network, "4"):
+		return ip.To4() != nil
+	case strings.HasSuffix(network, "6"):
+		return ip.To4() == nil
+	default:
+		return true
+	}
+}
@@ -0,0 +1,61 @@
+package hosts
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHostsDialContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(127, 0, 0, 1), "example.com"))).(*staticHosts)
+
+	for _, addr := range []string{
+		net.JoinHostPort("example.com", port),
+		net.JoinHostPort("127.0.0.1", port),
+	} {
+		conn, err := hosts.DialContext(context.Background(), "tcp", addr)
+		if err != nil {
+			t.Errorf("dial %s failed: %v", addr, err)
+			continue
+		}
+		if v := conn.RemoteAddr().String(); v != ln.Addr().String() {
+			t.Errorf("dial %s should connect to %s, got %s", addr, ln.Addr(), v)
+		}
+		conn.Close()
+	}
+
+	if _, err := hosts.DialContext(context.Background(), "tcp6", net.JoinHostPort("example.com", port)); err == nil {
+		t.Error("dial tcp6 should fail for an IPv4 only host")
+	}
+
+	// an IP address is dialed as is, rather than resolved by the catch-all entry.
+	hosts = NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 0, 2, 1), "*"))).(*staticHosts)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := hosts.DialContext(ctx, "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial %s failed: %v", ln.Addr(), err)
+	}
+	if v := conn.RemoteAddr().String(); v != ln.Addr().String() {
+		t.Errorf("dial %s should connect to %s, got %s", ln.Addr(), ln.Addr(), v)
+	}
+	conn.Close()
+}
@@ -0,0 +1,178 @@
+package hosts
+
+import (
+	"net"
+)
+
+// Diff compares the host tables old and new by hostname.
+// The entries of the hostnames only in new are returned as added,
+// the entries of the hostnames only in old are returned as removed,
+// and the entries in new of the hostnames whose addresses or aliases differ are returned as changed.
+// The addresses are compared with net.IP.Equal, and the order of the addresses and aliases is ignored.
+func Diff(old, new []Host) (added, removed, changed []Host) {
+	oldGroups, oldNames := groupHosts(old)
+	newGroups, newNames := groupHosts(new)
+
+	for _, name := range newNames {
+		g := newGroups[name]
+		v, ok := oldGroups[name]
+		switch {
+		case !ok:
+			added = append(added, g.hosts...)
+		case !g.equal(v):
+			changed = append(changed, g.hosts...)
+		}
+	}
+	for _, name := range oldNames {
+		if _, ok := newGroups[name]; !ok {
+			removed = append(removed, oldGroups[name].hosts...)
+		}
+	}
+	return
+}
+
+// hostGroup is the entries with the same hostname.
+type hostGroup struct {
+	hosts   []Host
+	ips     []net.IP
+	aliases map[string]struct{}
+}
+
+// groupHosts groups hosts by hostname, the hostnames are returned in the order they appear.
+func groupHosts(hosts []Host) (map[string]*hostGroup, []string) {
+	groups := make(map[string]*hostGroup)
+	var names []string
+	for _, host := range hosts {
+		name := normalize(host.Hostname)
+		g := groups[name]
+		if g == nil {
+			g = &hostGroup{aliases: make(map[string]struct{})}
+			groups[name] = g
+			names = append(names, name)
+		}
+		g.hosts = append(g.hosts, host)
+		if host.IP != nil {
+			g.ips = appendIP(g.ips, host.IP)
+		}
+		for _, alias := range host.Aliases {
+			g.aliases[normalize(alias)] = struct{}{}
+		}
+	}
+	return groups, names
+}
+
+// equal checks whether g and v have the same addresses and aliases.
+func (g *hostGroup) equal(v *hostGroup) bool {
+	if len(g.ips) != len(v.ips) || len(g.aliases) != len(v.aliases) {
+		return false
+	}
+	for _, ip := range g.ips {
+		if !containsIP(v.ips, ip) {
+			return false
+		}
+	}
+	for alias := range g.aliases {
+		if _, ok := v.aliases[alias]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// containsIP checks whether ips contains ip.
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, v := range ips {
+		if v.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal checks whether h and other have the same address, hostname and aliases.
+// The addresses are compared with net.IP.Equal along with their zones,
+// the names are compared case-insensitively, and the aliases are compared as sets.
+// The other fields, such as TTL and Comment, are ignored.
+func (h Host) Equal(other Host) bool {
+	if !h.IP.Equal(other.IP) || h.Zone != other.Zone || normalize(h.Hostname) != normalize(other.Hostname) {
+		return false
+	}
+	return containsNames(h.Aliases, other.Aliases) && containsNames(other.Aliases, h.Aliases)
+}
+
+// EqualHosts checks whether the host tables a and b have equal entries, see Host.Equal, in the same order.
+func EqualHosts(a, b []Host) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsNames checks whether every name of b is in a, case-insensitively.
+func containsNames(a, b []string) bool {
+	for _, name := range b {
+		name = normalize(name)
+		found := false
+		for _, v := range a {
+			if normalize(v) == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sameTable checks whether the tables a and b have the same entries, with the same names, weights, comments and tags,
+// the same networks, and the same options.
+// The tables with an entry with a TTL are never the same, as the expiry of the entry is renewed.
+func sameTable(a, b *table) bool {
+	if len(a.hosts) != len(b.hosts) || len(a.nets) != len(b.nets) ||
+		len(a.cnames) != len(b.cnames) || len(a.negated) != len(b.negated) {
+		return false
+	}
+	for i := range a.hosts {
+		x, y := &a.hosts[i], &b.hosts[i]
+		if x.TTL != 0 || y.TTL != 0 || !x.IP.Equal(y.IP) || x.Zone != y.Zone || x.Hostname != y.Hostname ||
+			!equalStrings(x.Aliases, y.Aliases) || x.Weight != y.Weight || x.Comment != y.Comment || !equalTags(x.Tags, y.Tags) {
+			return false
+		}
+	}
+	for i := range a.nets {
+		x, y := a.nets[i], b.nets[i]
+		if x.Net.String() != y.Net.String() || !equalStrings(x.Names, y.Names) {
+			return false
+		}
+	}
+	for k, v := range a.cnames {
+		if b.cnames[k] != v {
+			return false
+		}
+	}
+	for k, v := range a.negated {
+		if w, ok := b.negated[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
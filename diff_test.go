@@ -0,0 +1,128 @@
+package hosts
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var diffTests = []struct {
+	old, new                []Host
+	added, removed, changed []Host
+}{
+	{},
+	{
+		new: []Host{
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		},
+		added: []Host{
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		},
+	},
+	{
+		old: []Host{
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 2), "example.org"),
+		},
+		new: []Host{
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		},
+		removed: []Host{
+			NewHost(net.IPv4(192, 168, 1, 2), "example.org"),
+		},
+	},
+	{
+		old: []Host{
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com", "a", "b"),
+			NewHost(net.IPv4(192, 168, 1, 2), "example.com"),
+		},
+		new: []Host{
+			NewHost(net.ParseIP("192.168.1.2"), "EXAMPLE.com", "b"),
+			NewHost(net.ParseIP("192.168.1.1"), "example.com", "A"),
+		},
+	},
+	{
+		old: []Host{
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 2), "example.org", "a"),
+		},
+		new: []Host{
+			NewHost(net.IPv4(192, 168, 1, 3), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 2), "example.org", "b"),
+			NewHost(net.IPv4(192, 168, 1, 4), "example.net"),
+		},
+		added: []Host{
+			NewHost(net.IPv4(192, 168, 1, 4), "example.net"),
+		},
+		changed: []Host{
+			NewHost(net.IPv4(192, 168, 1, 3), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 2), "example.org", "b"),
+		},
+	},
+	{
+		old: []Host{
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		},
+		new: []Host{
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 2), "example.com"),
+		},
+		changed: []Host{
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 2), "example.com"),
+		},
+	},
+}
+
+func TestDiff(t *testing.T) {
+	for i, tc := range diffTests {
+		added, removed, changed := Diff(tc.old, tc.new)
+		if !reflect.DeepEqual(added, tc.added) {
+			t.Errorf("#%d test failed: added should be %v, got %v", i, tc.added, added)
+		}
+		if !reflect.DeepEqual(removed, tc.removed) {
+			t.Errorf("#%d test failed: removed should be %v, got %v", i, tc.removed, removed)
+		}
+		if !reflect.DeepEqual(changed, tc.changed) {
+			t.Errorf("#%d test failed: changed should be %v, got %v", i, tc.changed, changed)
+		}
+	}
+}
+
+var hostEqualTests = []struct {
+	a, b  Host
+	equal bool
+}{
+	{Host{}, Host{}, true},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com"), NewHost(net.IPv4(192, 168, 1, 1), "example.com"), true},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com"), NewHost(net.IPv4(192, 168, 1, 1).To4(), "Example.com"), true},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com"), NewHost(net.IPv4(192, 168, 1, 2), "example.com"), false},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com"), NewHost(net.IPv4(192, 168, 1, 1), "example.org"), false},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "a", "b"), NewHost(net.IPv4(192, 168, 1, 1), "example.com", "B", "a"), true},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "a", "a"), NewHost(net.IPv4(192, 168, 1, 1), "example.com", "a"), true},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "a"), NewHost(net.IPv4(192, 168, 1, 1), "example.com", "a", "b"), false},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "a"), NewHost(net.IPv4(192, 168, 1, 1), "example.com"), false},
+	{Host{IP: net.ParseIP("fe80::1"), Zone: "eth0"}, Host{IP: net.ParseIP("fe80::1"), Zone: "eth1"}, false},
+	{Host{IP: net.IPv4(192, 168, 1, 1), Hostname: "example.com", TTL: time.Hour}, NewHost(net.IPv4(192, 168, 1, 1), "example.com"), true},
+}
+
+func TestHostEqual(t *testing.T) {
+	for i, tc := range hostEqualTests {
+		if v := tc.a.Equal(tc.b); v != tc.equal {
+			t.Errorf("#%d test failed: equal should be %v, got %v", i, tc.equal, v)
+		}
+		if v := tc.b.Equal(tc.a); v != tc.equal {
+			t.Errorf("#%d test failed: reversed equal should be %v, got %v", i, tc.equal, v)
+		}
+	}
+
+	a := []Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com"), NewHost(net.IPv4(192, 168, 1, 2), "example.org")}
+	b := []Host{NewHost(net.IPv4(192, 168, 1, 1), "EXAMPLE.com"), NewHost(net.IPv4(192, 168, 1, 2), "example.org")}
+	if !EqualHosts(a, b) {
+		t.Errorf("%v should equal %v", a, b)
+	}
+	if EqualHosts(a, b[:1]) || EqualHosts(a, []Host{b[1], b[0]}) {
+		t.Error("tables of different entries or order should not be equal")
+	}
+}
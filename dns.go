@@ -0,0 +1,308 @@
+package hosts
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// dnsUDPSize is the maximum size of a response over UDP, a larger one is truncated to make the client retry over TCP.
+	dnsUDPSize = 512
+	// dnsTimeout is the timeout of forwarding a query to the upstream resolver, and of the idle TCP connections.
+	dnsTimeout = 5 * time.Second
+)
+
+// DNSServer serves the A and AAAA records of a host table over UDP and TCP.
+// The queries are answered with LookupAll, so that the reloads of the table take effect immediately.
+type DNSServer struct {
+	// Addr is the address to listen on by ListenAndServe, ":53" if empty.
+	Addr string
+	// Hosts is the host table answering the queries.
+	Hosts Hosts
+	// Upstream is the address of the resolver the queries of the names not found are forwarded to, such as 8.8.8.8:53,
+	// they are answered with NXDOMAIN if it is empty.
+	Upstream string
+	// TTL is the TTL of the answers, the zero TTL keeps the clients from caching them.
+	TTL time.Duration
+
+	mux     sync.Mutex
+	closers []io.Closer
+	closed  bool
+}
+
+// ListenAndServe listens on Addr over UDP and TCP, then serves the queries until the server is closed.
+func (s *DNSServer) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":53"
+	}
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		return err
+	}
+
+	errc := make(chan error, 2)
+	go func() { errc <- s.ServeUDP(pc) }()
+	go func() { errc <- s.ServeTCP(l) }()
+
+	err = <-errc
+	pc.Close()
+	l.Close()
+	if e := <-errc; err == nil {
+		err = e
+	}
+	return err
+}
+
+// ServeUDP serves the queries received on pc until the server is closed, pc is closed on return.
+func (s *DNSServer) ServeUDP(pc net.PacketConn) error {
+	if !s.track(pc) {
+		return nil
+	}
+	defer pc.Close()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return err
+		}
+		req := append([]byte(nil), buf[:n]...)
+		go func() {
+			if resp := s.answer(req, "udp"); resp != nil {
+				pc.WriteTo(resp, addr)
+			}
+		}()
+	}
+}
+
+// ServeTCP serves the queries of the connections accepted on l until the server is closed, l is closed on return.
+func (s *DNSServer) ServeTCP(l net.Listener) error {
+	if !s.track(l) {
+		return nil
+	}
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops the server, closing its listeners.
+func (s *DNSServer) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.closed = true
+	for _, c := range s.closers {
+		c.Close()
+	}
+	s.closers = nil
+	return nil
+}
+
+// track adds c to the listeners closed by Close, or closes c if the server is already closed.
+func (s *DNSServer) track(c io.Closer) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		c.Close()
+		return false
+	}
+	s.closers = append(s.closers, c)
+	return true
+}
+
+func (s *DNSServer) isClosed() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.closed
+}
+
+// serveConn serves the queries received on the TCP connection conn, until it is idle for dnsTimeout.
+func (s *DNSServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		conn.SetDeadline(time.Now().Add(dnsTimeout))
+		req, err := readTCPMsg(conn)
+		if err != nil {
+			return
+		}
+		resp := s.answer(req, "tcp")
+		if resp == nil {
+			return
+		}
+		if err := writeTCPMsg(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// answer returns the response to the query req received over network, or nil if req is not a valid query.
+func (s *DNSServer) answer(req []byte, network string) []byte {
+	var p dnsmessage.Parser
+	h, err := p.Start(req)
+	if err != nil || h.Response {
+		return nil
+	}
+	resp := dnsmessage.Header{
+		ID:               h.ID,
+		Response:         true,
+		OpCode:           h.OpCode,
+		RecursionDesired: h.RecursionDesired,
+	}
+	q, err := p.Question()
+	if err != nil {
+		resp.RCode = dnsmessage.RCodeFormatError
+		return s.reply(resp, nil, nil, network)
+	}
+	if h.OpCode != 0 {
+		resp.RCode = dnsmessage.RCodeNotImplemented
+		return s.reply(resp, &q, nil, network)
+	}
+
+	var ips []net.IP
+	if q.Class == dnsmessage.ClassINET && s.Hosts != nil {
+		ips = s.Hosts.LookupAll(strings.TrimSuffix(q.Name.String(), "."))
+	}
+	if len(ips) == 0 {
+		if s.Upstream != "" {
+			if b, err := s.forward(req, network); err == nil {
+				return b
+			}
+			resp.RCode = dnsmessage.RCodeServerFailure
+			return s.reply(resp, &q, nil, network)
+		}
+		resp.RCode = dnsmessage.RCodeNameError
+	}
+	resp.Authoritative = true
+	return s.reply(resp, &q, ips, network)
+}
+
+// reply builds the response with the header h, the question q, and the records of q.Type of ips.
+// The records are dropped from a response too large for UDP, which is then marked as truncated.
+func (s *DNSServer) reply(h dnsmessage.Header, q *dnsmessage.Question, ips []net.IP, network string) []byte {
+	b, err := s.build(h, q, ips)
+	if err == nil && network == "udp" && len(b) > dnsUDPSize {
+		h.Truncated = true
+		b, err = s.build(h, q, nil)
+	}
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func (s *DNSServer) build(h dnsmessage.Header, q *dnsmessage.Question, ips []net.IP) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, h)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if q == nil {
+		return b.Finish()
+	}
+	if err := b.Question(*q); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	rh := dnsmessage.ResourceHeader{
+		Name:  q.Name,
+		Class: dnsmessage.ClassINET,
+		TTL:   uint32(s.TTL / time.Second),
+	}
+	for _, ip := range ips {
+		ip4 := ip.To4()
+		switch {
+		case q.Type == dnsmessage.TypeA && ip4 != nil:
+			var r dnsmessage.AResource
+			copy(r.A[:], ip4)
+			if err := b.AResource(rh, r); err != nil {
+				return nil, err
+			}
+		case q.Type == dnsmessage.TypeAAAA && ip4 == nil:
+			var r dnsmessage.AAAAResource
+			copy(r.AAAA[:], ip)
+			if err := b.AAAAResource(rh, r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.Finish()
+}
+
+// forward forwards the query req to the upstream resolver over network, and returns its response.
+func (s *DNSServer) forward(req []byte, network string) ([]byte, error) {
+	conn, err := net.DialTimeout(network, s.Upstream, dnsTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsTimeout))
+
+	if network != "udp" {
+		if err := writeTCPMsg(conn, req); err != nil {
+			return nil, err
+		}
+		return readTCPMsg(conn)
+	}
+
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// readTCPMsg reads a message prefixed with its 2-byte length, as DNS over TCP.
+func readTCPMsg(r io.Reader) ([]byte, error) {
+	var n [2]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, err
+	}
+	b := make([]byte, binary.BigEndian.Uint16(n[:]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// writeTCPMsg writes b prefixed with its 2-byte length, as DNS over TCP.
+func writeTCPMsg(w io.Writer, b []byte) error {
+	msg := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(msg, uint16(len(b)))
+	copy(msg[2:], b)
+	_, err := w.Write(msg)
+	return err
+}
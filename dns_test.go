@@ -0,0 +1,239 @@
+package hosts
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startDNSServer serves s on the loopback address over UDP and TCP, and returns the address.
+func startDNSServer(t *testing.T, s *DNSServer) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Fatal(err)
+	}
+	go s.ServeUDP(pc)
+	go s.ServeTCP(l)
+	return pc.LocalAddr().String()
+}
+
+// queryDNS sends the query of name and typ to addr over network, and returns the response.
+func queryDNS(t *testing.T, network, addr, name string, typ dnsmessage.Type) (dnsmessage.Header, []net.IP) {
+	t.Helper()
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 1, RecursionDesired: true})
+	b.StartQuestions()
+	b.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET})
+	req, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var resp []byte
+	if network == "udp" {
+		if _, err := conn.Write(req); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp = buf[:n]
+	} else {
+		if err := writeTCPMsg(conn, req); err != nil {
+			t.Fatal(err)
+		}
+		if resp, err = readTCPMsg(conn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		t.Fatal(err)
+	}
+	var ips []net.IP
+	for _, r := range msg.Answers {
+		switch body := r.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]))
+		}
+	}
+	return msg.Header, ips
+}
+
+var dnsServerTests = []struct {
+	network string
+	name    string
+	typ     dnsmessage.Type
+	rcode   dnsmessage.RCode
+	ips     []net.IP
+}{
+	{"udp", "example.com.", dnsmessage.TypeA, dnsmessage.RCodeSuccess, []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}},
+	{"tcp", "example.com.", dnsmessage.TypeA, dnsmessage.RCodeSuccess, []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}},
+	{"udp", "example.com.", dnsmessage.TypeAAAA, dnsmessage.RCodeSuccess, []net.IP{net.ParseIP("2001:db8::1")}},
+	{"udp", "www.example.org.", dnsmessage.TypeA, dnsmessage.RCodeSuccess, []net.IP{net.IPv4(192, 168, 1, 3)}},
+	{"udp", "www.example.org.", dnsmessage.TypeAAAA, dnsmessage.RCodeSuccess, nil},
+	{"udp", "example.com.", dnsmessage.TypeMX, dnsmessage.RCodeSuccess, nil},
+	{"udp", "example.net.", dnsmessage.TypeA, dnsmessage.RCodeNameError, nil},
+	{"tcp", "example.net.", dnsmessage.TypeA, dnsmessage.RCodeNameError, nil},
+}
+
+func TestDNSServer(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		NewHost(net.IPv4(192, 168, 1, 2), "example.com"),
+		NewHost(net.ParseIP("2001:db8::1"), "example.com"),
+		NewHost(net.IPv4(192, 168, 1, 3), "*.example.org"),
+	))
+	s := &DNSServer{Hosts: hosts}
+	defer s.Close()
+	addr := startDNSServer(t, s)
+
+	for i, tc := range dnsServerTests {
+		h, ips := queryDNS(t, tc.network, addr, tc.name, tc.typ)
+		if h.ID != 1 || !h.Response || !h.Authoritative || h.RCode != tc.rcode || !equalIPs(ips, tc.ips) {
+			t.Errorf("#%d test failed: response should be %s %v, got %+v %v", i, tc.rcode, tc.ips, h, ips)
+		}
+	}
+
+	hosts.(*staticHosts).Reload(strings.NewReader("192.168.1.4 example.net"))
+	if h, ips := queryDNS(t, "udp", addr, "example.net.", dnsmessage.TypeA); h.RCode != dnsmessage.RCodeSuccess ||
+		!equalIPs(ips, []net.IP{net.IPv4(192, 168, 1, 4)}) {
+		t.Errorf("response should be %s after reload, got %+v %v", net.IPv4(192, 168, 1, 4), h, ips)
+	}
+}
+
+func TestDNSServerUpstream(t *testing.T) {
+	upstream := &DNSServer{Hosts: NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 2), "example.org")))}
+	defer upstream.Close()
+
+	s := &DNSServer{
+		Hosts:    NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))),
+		Upstream: startDNSServer(t, upstream),
+	}
+	defer s.Close()
+	addr := startDNSServer(t, s)
+
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	for _, host := range []string{"example.com", "example.org"} {
+		addrs, err := r.LookupHost(context.Background(), host+".")
+		if err != nil || len(addrs) != 1 {
+			t.Errorf("lookup of %s should be found, got %v, %v", host, addrs, err)
+		}
+	}
+	for _, network := range []string{"udp", "tcp"} {
+		h, _ := queryDNS(t, network, addr, "example.net.", dnsmessage.TypeA)
+		if h.RCode != dnsmessage.RCodeNameError {
+			t.Errorf("response over %s should be forwarded as %s, got %s", network, dnsmessage.RCodeNameError, h.RCode)
+		}
+	}
+}
+
+func TestDNSServerTruncate(t *testing.T) {
+	var hs []Host
+	for i := 0; i < 64; i++ {
+		hs = append(hs, NewHost(net.IPv4(192, 168, 1, byte(i)), "example.com"))
+	}
+	s := &DNSServer{Hosts: NewHosts(WithInitialHosts(hs...))}
+	defer s.Close()
+	addr := startDNSServer(t, s)
+
+	if h, ips := queryDNS(t, "udp", addr, "example.com.", dnsmessage.TypeA); !h.Truncated || len(ips) != 0 {
+		t.Errorf("response over udp should be truncated, got %+v with %d answers", h, len(ips))
+	}
+	h, ips := queryDNS(t, "tcp", addr, "example.com.", dnsmessage.TypeA)
+	if h.Truncated || len(ips) != len(hs) {
+		t.Errorf("response over tcp should have %d answers, got %+v with %d answers", len(hs), h, len(ips))
+	}
+
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	addrs, err := r.LookupHost(context.Background(), "example.com.")
+	if err != nil || len(addrs) != len(hs) {
+		t.Errorf("lookup should retry over tcp, got %d addresses, %v", len(addrs), err)
+	}
+}
+
+func TestDNSServerClose(t *testing.T) {
+	s := &DNSServer{Addr: "127.0.0.1:0", Hosts: NewHosts()}
+	errc := make(chan error, 1)
+	go func() { errc <- s.ListenAndServe() }()
+
+	s.Close()
+	if err := <-errc; err != nil {
+		t.Errorf("serve should return nil after close, got %v", err)
+	}
+}
+
+func TestHostsLookupWithFallback(t *testing.T) {
+	upstream := &DNSServer{Hosts: NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 2), "example.com"),
+		NewHost(net.IPv4(192, 168, 1, 3), "example.org"),
+	))}
+	defer upstream.Close()
+	addr := startDNSServer(t, upstream)
+	fallback := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))).(*staticHosts)
+	tests := []struct {
+		host     string
+		fallback *net.Resolver
+		ips      []net.IP
+		err      bool
+	}{
+		{"example.com", fallback, []net.IP{net.IPv4(192, 168, 1, 1)}, false},
+		{"example.org.", fallback, []net.IP{net.IPv4(192, 168, 1, 3)}, false},
+		{"example.org.:80", fallback, []net.IP{net.IPv4(192, 168, 1, 3)}, false},
+		{"example.net.", fallback, nil, true},
+		{"example.org.", nil, nil, false},
+	}
+	for i, tc := range tests {
+		ips, err := hosts.LookupWithFallback(context.Background(), tc.host, tc.fallback)
+		if !equalIPs(ips, tc.ips) || (err != nil) != tc.err {
+			t.Errorf("#%d test failed: lookup of %s should be %v, got %v, %v", i, tc.host, tc.ips, ips, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := hosts.LookupWithFallback(ctx, "example.com", fallback); err != context.Canceled {
+		t.Errorf("lookup should be canceled, got %v", err)
+	}
+}
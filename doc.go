@@ -0,0 +1,57 @@
+// Package hosts is a static table lookup for hostnames, loaded from a config in the format of /etc/hosts.
+//
+// For each host a single line should be present with the following information:
+//
+//	IP_address canonical_hostname [aliases...]
+//
+// Fields of the entry are separated by any number of blanks and/or tab characters, or the separators set by WithSeparators.
+// A field other than a name may be quoted to contain the separators, such as the path of the include option,
+// e.g. include "/etc/my hosts", and a backslash escapes the following separator, quote or backslash.
+// A name never contains the separators, as it must be a valid domain name.
+//
+// # Addresses
+//
+// The fields which are IP addresses are all addresses of the entry, so an entry can have more than one address,
+// e.g. "example.com 192.168.1.1 192.168.1.2 ::1", an IPv6 address may have a zone, e.g. "fe80::1%eth0".
+// An address may have a weight for LookupWeighted, e.g. "10.0.0.1#w=3", the addresses without one have the weight 1.
+// The fields which are CIDR notations, e.g. "10.0.0.0/8 corp", are networks, their names are only used by ReverseLookupNet.
+//
+// # Names
+//
+// A hostname or alias of the form *.example.com matches every subdomain of example.com.
+// A hostname or alias of the form re:expr matches the names matched entirely by the regular expression expr,
+// and one of the form glob:pattern matches the names matched by the shell pattern, e.g. glob:*.cdn.*.example.com,
+// where * and ? match within a label. The names are matched in their normalized (lower cased by default) form,
+// after the exact names and wildcards, in the order the patterns appear.
+// The patterns are case-insensitive unless the names are matched case-sensitively, see WithCaseInsensitive.
+//
+// An entry of the negated names, e.g. "!good.example.com", makes the names only match the entries of the exact names,
+// so that they are not resolved by any wildcard, pattern, cname option or the catch-all entry.
+// The entry with the hostname "*" is the catch-all entry, which matches the names not matched by any other entry,
+// only the last catch-all entry of the config is kept.
+// The queries of IP addresses, such as 8.8.8.8, are never matched by a wildcard, pattern or the catch-all entry.
+//
+// The other hostnames and aliases must be valid domain names per RFC 1123, with underscores allowed by WithUnderscores,
+// the entries with an invalid name are skipped, or fail the reload with WithStrict.
+// Entries identical to an earlier one, with the same IP, hostname and aliases, are dropped on reload,
+// and a name defined again on another line is resolved by the strategy set by WithConflictStrategy.
+// Names are matched case-insensitively by default.
+//
+// # Options
+//
+// The line "reload <duration>" sets the reload period, which is reset by a later config without one unless it is set by SetPeriod,
+// the line "ttl <duration>" sets the TTL of the subsequent entries,
+// the line "include <path>" parses the file at path in place,
+// and the line "cname <hostname> <aliases...>" makes the aliases resolve to the entries of hostname,
+// the entries of a name take precedence over its cname, and a chain of at most 8 aliases is followed.
+// The include option is refused in the remote configs of ReloadURL.
+//
+// # Comments and tags
+//
+// Text from a "#" character, or the one set by WithCommentChar, until the end of the line is a comment,
+// and is ignored unless retained by WithComments.
+// The trailing fields of the form #key=value, e.g. "0.0.0.0 ads.example.com #category=ads", are the tags of the entry,
+// they follow the comment of the entry if any, and start with the comment character set by WithCommentChar.
+//
+// References to variables, $VAR or ${VAR}, are replaced by the values of the environment variables by default, see WithExpand.
+package hosts
@@ -0,0 +1,67 @@
+package hosts
+
+import (
+	"time"
+)
+
+// withExpires returns h with its expiry time set from now, if it has a TTL.
+func (h Host) withExpires(now time.Time) Host {
+	h.expires = time.Time{}
+	if h.TTL > 0 {
+		h.expires = now.Add(h.TTL)
+	}
+	return h
+}
+
+// expired checks whether h is expired. The current time is read into *now if it is zero,
+// so that it can be reused for checking multiple entries.
+func (h *Host) expired(now *time.Time) bool {
+	if h.expires.IsZero() {
+		return false
+	}
+	if now.IsZero() {
+		*now = time.Now()
+	}
+	return !now.Before(h.expires)
+}
+
+// setExpires returns a copy of hosts with their expiry times set from now.
+func setExpires(hosts []Host, now time.Time) []Host {
+	if hosts == nil {
+		return nil
+	}
+	v := make([]Host, len(hosts))
+	for i := range hosts {
+		v[i] = hosts[i].withExpires(now)
+	}
+	return v
+}
+
+// nextExpires returns the earliest expiry time of the entries, or zero time if no entry expires.
+func (h *staticHosts) nextExpires() (t time.Time) {
+	hosts := h.load().hosts
+	for i := range hosts {
+		if e := hosts[i].expires; !e.IsZero() && (t.IsZero() || e.Before(t)) {
+			t = e
+		}
+	}
+	return
+}
+
+// sweep removes the expired entries from the host table.
+func (h *staticHosts) sweep() {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	var now time.Time
+	all := h.load().hosts
+	hosts := make([]Host, 0, len(all))
+	for _, host := range all {
+		if !host.expired(&now) {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) < len(all) {
+		h.setHosts(hosts)
+	}
+}
@@ -0,0 +1,62 @@
+package hosts
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostsTTL(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		Host{IP: net.IPv4(192, 168, 1, 2), Hostname: "example.com", TTL: time.Hour},
+		Host{IP: net.IPv4(192, 168, 1, 3), Hostname: "example.com", TTL: -time.Hour},
+	)).(*staticHosts)
+	hosts.Add(Host{IP: net.IPv4(192, 168, 1, 4), Hostname: "example.com", TTL: time.Millisecond})
+
+	time.Sleep(10 * time.Millisecond)
+
+	expected := []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2), net.IPv4(192, 168, 1, 3)}
+	if ips := hosts.LookupAll("example.com"); !equalIPs(ips, expected) {
+		t.Errorf("lookup should be %v, got %v", expected, ips)
+	}
+	if names := hosts.ReverseLookup(net.IPv4(192, 168, 1, 4)); names != nil {
+		t.Errorf("reverse lookup should be %v, got %v", nil, names)
+	}
+	if n := hosts.Count(); n != 4 {
+		t.Errorf("count should be %d before sweep, got %d", 4, n)
+	}
+
+	hosts.sweep()
+	if n := hosts.Count(); n != 3 {
+		t.Errorf("count should be %d after sweep, got %d", 3, n)
+	}
+}
+
+func TestHostsTTLReload(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	r := "192.168.1.1 example.com\nttl 300ms\n192.168.1.2 example.com\nttl 0\n192.168.1.3 example.com"
+	hosts.Run(func() (io.Reader, error) {
+		return strings.NewReader(r), nil
+	})
+
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 1))
+	if s, expected := hosts.String(), "192.168.1.1 example.com\nttl 300ms\n192.168.1.2 example.com\nttl 0s\n192.168.1.3 example.com\n"; s != expected {
+		t.Errorf("output should be %q, got %q", expected, s)
+	}
+
+	for i := 0; i < 100 && hosts.Count() != 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := hosts.Count(); n != 2 {
+		t.Errorf("count should be %d after the entry expires, got %d", 2, n)
+	}
+	expected := []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 3)}
+	if ips := hosts.LookupAll("example.com"); !equalIPs(ips, expected) {
+		t.Errorf("lookup should be %v, got %v", expected, ips)
+	}
+}
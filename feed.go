@@ -0,0 +1,41 @@
+package hosts
+
+// Feed starts a goroutine which replaces the host table with each list of entries received from ch,
+// until ch is closed or the reloader is stopped.
+// The entries are checked like the reloaded ones, the invalid entries are skipped and retrieved by Errors,
+// the Line of the ParseError of an invalid entry is its 1-based position in the list.
+// The reload period is left as is.
+func (h *staticHosts) Feed(ch <-chan []Host) {
+	done := h.done()
+
+	h.spawn(func() {
+		for {
+			select {
+			case hosts, ok := <-ch:
+				if !ok {
+					return
+				}
+				h.reloadHosts(hosts)
+			case <-done:
+				return
+			}
+		}
+	})
+}
+
+// reloadHosts replaces the host table with hosts.
+func (h *staticHosts) reloadHosts(hosts []Host) error {
+	return h.reload(func(p *parser) error {
+		for i, host := range hosts {
+			p.line, p.text = i+1, host.String()
+			p.hash(p.text + " " + host.TTL.String())
+			if err := p.addHost(host.clone()); err != nil {
+				p.report(ParseError{Line: p.line, Text: p.text, Err: err})
+			}
+			if p.err != nil {
+				return p.err
+			}
+		}
+		return nil
+	})
+}
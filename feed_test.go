@@ -0,0 +1,63 @@
+package hosts
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostsFeed(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	var reloads int32
+	hosts.OnReload(func(count int) {
+		atomic.AddInt32(&reloads, 1)
+	})
+
+	ch := make(chan []Host)
+	hosts.Feed(ch)
+
+	ch <- []Host{
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		NewHost(nil, "example.org"),
+	}
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 1))
+	if errs := hosts.Errors(); len(errs) != 1 || errs[0].Line != 2 {
+		t.Errorf("errors should be line 2, got %v", errs)
+	}
+
+	ch <- []Host{NewHost(net.IPv4(192, 168, 1, 2), "example.org")}
+	waitLookup(t, hosts, "example.org", net.IPv4(192, 168, 1, 2))
+	if ip := hosts.Lookup("example.com"); ip != nil {
+		t.Errorf("lookup should be nil, got %s", ip)
+	}
+	for i := 0; i < 50 && atomic.LoadInt32(&reloads) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := atomic.LoadInt32(&reloads); v != 2 {
+		t.Errorf("reloads should be 2, got %d", v)
+	}
+
+	close(ch)
+}
+
+func TestHostsFeedStop(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+
+	ch := make(chan []Host, 1)
+	hosts.Feed(ch)
+	hosts.Stop()
+	time.Sleep(10 * time.Millisecond)
+
+	select {
+	case ch <- []Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}:
+	default:
+		t.Fatal("channel should be writable")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ip := hosts.Lookup("example.com"); ip != nil {
+		t.Errorf("lookup should be nil after stop, got %s", ip)
+	}
+}
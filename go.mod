@@ -1,3 +1,9 @@
 module github.com/go-gost/hosts
 
 go 1.13
+
+require (
+	github.com/fsnotify/fsnotify v1.4.9
+	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110
+	gopkg.in/yaml.v3 v3.0.1
+)
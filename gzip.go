@@ -0,0 +1,37 @@
+package hosts
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+)
+
+// ReloadGzip is like Reload, but parses config from the gzip-compressed r.
+// An error is returned if r is not valid gzip data, leaving the host table unchanged.
+func (h *staticHosts) ReloadGzip(r io.Reader) error {
+	if r == nil {
+		return nil
+	}
+	return h.reload(func(p *parser) error {
+		return parseGzip(p, r)
+	})
+}
+
+// parseGzip parses config from the gzip-compressed r with p.
+func parseGzip(p *parser, r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return p.parse(zr)
+}
+
+// gzipMagic is the header of the gzip data.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// isGzip peeks at the beginning of r to check whether it is gzip data.
+func isGzip(r *bufio.Reader) bool {
+	b, err := r.Peek(len(gzipMagic))
+	return err == nil && b[0] == gzipMagic[0] && b[1] == gzipMagic[1]
+}
@@ -0,0 +1,95 @@
+package hosts
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func gzipData(t *testing.T, s string) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestHostsReloadGzip(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.ReloadGzip(bytes.NewReader(gzipData(t, "192.168.1.1 example.com"))); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+
+	data := gzipData(t, strings.Repeat("192.168.1.2 example.com\n", 1000))
+	for i, r := range []*bytes.Reader{
+		bytes.NewReader([]byte("192.168.1.2 example.com")),
+		bytes.NewReader(data[:len(data)-8]),
+	} {
+		if err := hosts.ReloadGzip(r); err == nil {
+			t.Errorf("#%d test failed: reload should fail for invalid gzip data", i)
+		}
+		if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+			t.Errorf("#%d test failed: lookup should be %s, got %s", i, net.IPv4(192, 168, 1, 1), ip)
+		}
+	}
+}
+
+func TestHostsReloadGzipLocal(t *testing.T) {
+	f, err := ioutil.TempFile("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("192.168.1.3 example.net\n")
+	f.Close()
+	os.Setenv("HOSTS_TEST_NAME", "local")
+	defer os.Unsetenv("HOSTS_TEST_NAME")
+
+	// a local gzip config is parsed like the one of Reload.
+	config := "include " + f.Name() + "\n" +
+		"192.168.1.2 ${HOSTS_TEST_NAME}.example.org\n"
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.ReloadGzip(bytes.NewReader(gzipData(t, config))); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.net"); !ip.Equal(net.IPv4(192, 168, 1, 3)) {
+		t.Errorf("lookup of the included entry should be %s, got %s", net.IPv4(192, 168, 1, 3), ip)
+	}
+	if ip := hosts.Lookup("local.example.org"); !ip.Equal(net.IPv4(192, 168, 1, 2)) {
+		t.Errorf("lookup of the expanded entry should be %s, got %s", net.IPv4(192, 168, 1, 2), ip)
+	}
+	if errs := hosts.Errors(); len(errs) > 0 {
+		t.Errorf("reload should have no errors, got %v", errs)
+	}
+}
+
+func TestHostsReloadURLGzip(t *testing.T) {
+	data := gzipData(t, "192.168.1.1 example.com")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.ReloadURL(context.Background(), srv.URL+"/hosts.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+}
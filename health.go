@@ -0,0 +1,66 @@
+package hosts
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// healthTTL is the time the result of a health check is cached for.
+const healthTTL = 5 * time.Second
+
+// healthChecker caches the results of the health check of the addresses set by WithHealthCheck.
+type healthChecker struct {
+	check   func(net.IP) bool
+	ttl     time.Duration
+	results map[string]healthResult // by ipKey
+	mux     sync.Mutex
+}
+
+type healthResult struct {
+	healthy bool
+	expires time.Time
+}
+
+func newHealthChecker(check func(net.IP) bool) *healthChecker {
+	return &healthChecker{
+		check:   check,
+		ttl:     healthTTL,
+		results: make(map[string]healthResult),
+	}
+}
+
+// healthy returns the cached result of the health check of ip, or checks ip if the result is missing or expired.
+// The lock is not held while checking, so that a slow check does not block the other lookups.
+func (c *healthChecker) healthy(ip net.IP) bool {
+	k := ipKey(ip)
+	now := time.Now()
+
+	c.mux.Lock()
+	r, ok := c.results[k]
+	c.mux.Unlock()
+	if ok && now.Before(r.expires) {
+		return r.healthy
+	}
+
+	r = healthResult{healthy: c.check(ip), expires: now.Add(c.ttl)}
+	c.mux.Lock()
+	c.results[k] = r
+	c.mux.Unlock()
+	return r.healthy
+}
+
+// filter returns the healthy addresses of ips, or ips if none of them is healthy,
+// so that a failing health check never makes a host unresolvable.
+func (c *healthChecker) filter(ips []net.IP) []net.IP {
+	v := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if c.healthy(ip) {
+			v = append(v, ip)
+		}
+	}
+	if len(v) == 0 {
+		return ips
+	}
+	return v
+}
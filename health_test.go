@@ -0,0 +1,65 @@
+package hosts
+
+import (
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestHostsHealthCheck(t *testing.T) {
+	var mux sync.Mutex
+	down := map[string]bool{"192.168.1.2": true}
+	checks := 0
+	check := func(ip net.IP) bool {
+		mux.Lock()
+		defer mux.Unlock()
+
+		checks++
+		return !down[ip.String()]
+	}
+
+	hosts := NewHosts(
+		WithInitialHosts(
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 2), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 3), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 2), "example.org"),
+		),
+		WithHealthCheck(check),
+	).(*staticHosts)
+
+	expected := []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 3)}
+	if ips := hosts.LookupAll("example.com"); !equalIPs(ips, expected) {
+		t.Errorf("lookup should be %v, got %v", expected, ips)
+	}
+	for i := 0; i < 4; i++ {
+		if ip := hosts.LookupRoundRobin("example.com"); ip.Equal(net.IPv4(192, 168, 1, 2)) {
+			t.Errorf("#%d test failed: round robin should skip %s", i, ip)
+		}
+	}
+	if checks != 3 {
+		t.Errorf("checks should be cached, got %d checks", checks)
+	}
+
+	// a single address is not checked, and one failing all the checks is kept
+	if ips := hosts.LookupAll("example.org"); !equalIPs(ips, []net.IP{net.IPv4(192, 168, 1, 2)}) {
+		t.Errorf("lookup should be %s, got %v", net.IPv4(192, 168, 1, 2), ips)
+	}
+	mux.Lock()
+	down["192.168.1.1"], down["192.168.1.3"] = true, true
+	mux.Unlock()
+	hosts.health.ttl = 0 // drop the cached results
+	hosts.health.results = make(map[string]healthResult)
+	expected = []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2), net.IPv4(192, 168, 1, 3)}
+	if ips := hosts.LookupAll("example.com"); !equalIPs(ips, expected) {
+		t.Errorf("lookup of the failing addresses should be %v, got %v", expected, ips)
+	}
+
+	mux.Lock()
+	down = map[string]bool{"192.168.1.1": true}
+	mux.Unlock()
+	expected = []net.IP{net.IPv4(192, 168, 1, 2), net.IPv4(192, 168, 1, 3)}
+	if ips := hosts.LookupAll("example.com"); !equalIPs(ips, expected) {
+		t.Errorf("lookup of the recovered addresses should be %v, got %v", expected, ips)
+	}
+}
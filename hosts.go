@@ -1,19 +1,42 @@
 package hosts
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"io"
+	"io/ioutil"
 	"net"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Host is a static mapping from hostname to IP.
 type Host struct {
-	IP       net.IP
+	IP net.IP
+	// Zone is the IPv6 zone of IP, such as eth0 of fe80::1%eth0, it is retrieved by LookupIPAddr.
+	Zone     string
 	Hostname string
 	Aliases  []string
+	// TTL is the lifetime of the entry from when it is added to the host table,
+	// a zero TTL means the entry never expires.
+	TTL time.Duration
+	// Comment is the trailing comment of the entry, without the comment character,
+	// it is only retained by Reload with WithComments.
+	Comment string
+	// Source is the path of the file the entry is parsed from by ReloadFile or the include option,
+	// it is empty for the entries not parsed from a file.
+	Source string
+	// Weight is the relative weight of the address for LookupWeighted, zero is the default weight 1.
+	Weight int
+	// Tags are the key/value metadata of the entry, such as the category of a block list,
+	// they are parsed from the trailing fields of the form #key=value, see LookupByTag.
+	Tags map[string]string
+
+	expires time.Time
 }
 
 // NewHost creates a Host.
@@ -25,100 +48,699 @@ func NewHost(ip net.IP, hostname string, aliases ...string) Host {
 	}
 }
 
+// clone returns a deep copy of h.
+func (h Host) clone() Host {
+	h.IP = copyIP(h.IP)
+	if h.Aliases != nil {
+		h.Aliases = append([]string(nil), h.Aliases...)
+	}
+	h.Tags = copyTags(h.Tags)
+	return h
+}
+
 // Hosts is an interface that performs static table lookup for host name.
 type Hosts interface {
 	Lookup(host string) net.IP
+	LookupAll(host string) []net.IP
+	ReverseLookup(ip net.IP) []string
+	Exists(host string) bool
+	HasIP(ip net.IP) bool
 }
 
-// hosts is a static table lookup for hostnames.
-// For each host a single line should be present with the following information:
-// IP_address canonical_hostname [aliases...]
-// Fields of the entry are separated by any number of blanks and/or tab characters.
-// Text from a "#" character until the end of the line is a comment, and is ignored.
+// hosts is a static table lookup for hostnames,
+// parsed from the config in the format described in the package documentation.
 type staticHosts struct {
-	hosts   []Host
-	period  time.Duration
-	stopped chan struct{}
-	mux     sync.RWMutex
+	counters counters
+
+	table        atomic.Value // *table, replaced as a whole so that the lookups need no lock
+	period       time.Duration
+	configPeriod bool // the period is set by the reload option of the last config, rather than by SetPeriod
+	errs         []ParseError
+	mux          sync.RWMutex // serializes the changes of the table, and guards the other fields
+
+	stopped       chan struct{}             // closed when the reloader is stopped, replaced when it is started again
+	source        func() (io.Reader, error) // the source of Run, resumed by Start
+	periodChanged chan struct{}             // signaled by SetPeriod to wake the reloader
+	stopMux       sync.Mutex
+	goroutines    sync.WaitGroup // the goroutines of the reloader, waited for by Wait
+
+	options    options
+	key        func(string) string // converts the names to their keys in the index
+	callbacks  []func(count int)
+	reloadMux  sync.Mutex
+	validators cacheValidators
+	checksum   []byte        // checksum of the config of the last reload, nil if the table is changed since
+	ready      chan struct{} // closed after the first successful reload
+	readyOnce  sync.Once
+	subs       subscriptions
+	cache      *lookupCache   // nil if the lookup cache is disabled
+	pinned     []Host         // the entries merged after each reload
+	health     *healthChecker // nil if the addresses are not checked
+	modified   time.Time      // the time the table is last changed, zero if never
+	changed    bool           // the last reload changed the table
 }
 
-// NewHosts creates a Hosts with optional list of hosts.
-func NewHosts(hosts ...Host) Hosts {
-	return &staticHosts{
-		hosts:   hosts,
-		stopped: make(chan struct{}),
+// NewHosts creates a Hosts with the options.
+// The initial entries of the host table are set by WithInitialHosts,
+// so NewHosts(hosts...) of the earlier versions becomes NewHosts(WithInitialHosts(hosts...)).
+func NewHosts(opts ...Option) Hosts {
+	options := defaultOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	h := &staticHosts{
+		stopped:       make(chan struct{}),
+		ready:         make(chan struct{}),
+		periodChanged: make(chan struct{}, 1),
+		options:       options,
+		key:           options.key(),
 	}
+	if options.cacheSize > 0 {
+		h.cache = newLookupCache(options.cacheSize)
+	}
+	if options.healthCheck != nil {
+		h.health = newHealthChecker(options.healthCheck)
+	}
+	h.table.Store(newTable(setExpires(options.hosts, time.Now()), nil, h.key))
+	h.options.hosts = nil
+
+	return h
 }
 
 // Lookup searches the IP address corresponds to the given host from the host table.
-func (h *staticHosts) Lookup(host string) (ip net.IP) {
+// If the host has more than one address, the first one is returned.
+// The host may have a port, such as example.com:80, which is ignored.
+func (h *staticHosts) Lookup(host string) net.IP {
+	ip, _ := h.LookupContext(context.Background(), host)
+	return ip
+}
+
+// LookupContext is like Lookup, but returns ctx.Err() if ctx is done before the lookup completes.
+// A nil IP with a nil error means the host is not found.
+func (h *staticHosts) LookupContext(ctx context.Context, host string) (net.IP, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	if h != nil && h.options.tracer != nil {
+		return h.lookupTraced(ctx, host), nil
+	}
+	return h.lookupFirst(host), nil
+}
+
+// lookupFirst returns the first address of host, or nil if host is not found.
+func (h *staticHosts) lookupFirst(host string) net.IP {
+	if ips := h.LookupAll(host); len(ips) > 0 {
+		return ips[0]
+	}
+	return nil
+}
+
+// LookupAll searches all the IP addresses correspond to the given host from the host table.
+// The addresses are returned in the order they appear in the table, duplicates are removed,
+// they are copies which the caller is free to modify.
+func (h *staticHosts) LookupAll(host string) []net.IP {
 	if h == nil || host == "" {
-		return
+		return nil
 	}
 
-	h.mux.RLock()
-	defer h.mux.RUnlock()
+	return h.lookupAll(h.load(), host)
+}
+
+// BatchLookup is like Lookup, but looks up the hosts at once in the same version of the host table.
+// The hosts which are not found are absent from the result.
+func (h *staticHosts) BatchLookup(hosts []string) map[string]net.IP {
+	m := make(map[string]net.IP, len(hosts))
+	if h == nil {
+		return m
+	}
 
-	for _, h := range h.hosts {
-		if h.Hostname == host {
-			ip = h.IP
-			break
+	t := h.load()
+	for _, host := range hosts {
+		if host == "" {
+			continue
 		}
-		for _, alias := range h.Aliases {
-			if alias == host {
-				ip = h.IP
-				break
+		if ips := h.lookupAll(t, host); len(ips) > 0 {
+			m[host] = ips[0]
+		}
+	}
+	return m
+}
+
+// lookupAll is like LookupAll, but looks up host in t.
+func (h *staticHosts) lookupAll(t *table, host string) (ips []net.IP) {
+	start := h.startLookup()
+	var now time.Time
+	for _, i := range h.lookupIndexes(t, host) {
+		if t.hosts[i].expired(&now) {
+			continue
+		}
+		ips = appendIP(ips, t.hosts[i].IP)
+	}
+	h.counters.lookup(len(ips) > 0, start)
+	if h.health != nil && len(ips) > 1 {
+		ips = h.health.filter(ips)
+	}
+	return
+}
+
+// lookupHost returns the first entry of t matching host.
+func (h *staticHosts) lookupHost(t *table, host string) (*Host, bool) {
+	var now time.Time
+	for _, i := range h.lookupIndexes(t, host) {
+		if !t.hosts[i].expired(&now) {
+			return &t.hosts[i], true
+		}
+	}
+	return nil, false
+}
+
+// GetHost returns a copy of the first entry matching host, by its hostname or one of its aliases,
+// and whether the entry is found.
+func (h *staticHosts) GetHost(host string) (Host, bool) {
+	if h == nil || host == "" {
+		return Host{}, false
+	}
+
+	v, ok := h.lookupHost(h.load(), host)
+	if !ok {
+		return Host{}, false
+	}
+	return v.clone(), true
+}
+
+// Canonical returns the canonical hostname of the entry matching host,
+// which is host itself if it is a canonical hostname, or an empty string if host is not found.
+// If the canonical hostname of the entry is a wildcard, a pattern or the catch-all name, host is returned.
+func (h *staticHosts) Canonical(host string) string {
+	if h == nil || host == "" {
+		return ""
+	}
+
+	v, ok := h.lookupHost(h.load(), host)
+	if !ok {
+		return ""
+	}
+	if strings.HasPrefix(v.Hostname, "*") || isPattern(v.Hostname) {
+		return host
+	}
+	return v.Hostname
+}
+
+// Aliases returns the aliases of the canonical hostname of the entry matching host, see Canonical,
+// which are the aliases of all the entries with the canonical hostname in the order they appear,
+// followed by the aliases of the canonical hostname defined by the cname option in sorted order.
+// It returns nil if host is not found or has no aliases, the returned slice is a copy.
+func (h *staticHosts) Aliases(host string) []string {
+	if h == nil || host == "" {
+		return nil
+	}
+
+	t := h.load()
+	v, ok := h.lookupHost(t, host)
+	if !ok {
+		return nil
+	}
+	canonical := h.key(v.Hostname)
+	var aliases []string
+	for _, alias := range v.Aliases {
+		aliases = appendName(aliases, alias)
+	}
+	var now time.Time
+	for _, i := range t.index.lookupExact(v.Hostname) {
+		if e := &t.hosts[i]; h.key(e.Hostname) == canonical && !e.expired(&now) {
+			for _, alias := range e.Aliases {
+				aliases = appendName(aliases, alias)
+			}
+		}
+	}
+	if i := len(aliases); len(t.cnames) > 0 {
+		for _, c := range t.cnames {
+			if h.key(c.target) == canonical {
+				aliases = appendName(aliases, c.name)
 			}
 		}
+		sort.Strings(aliases[i:])
+	}
+	return aliases
+}
+
+// LookupRoundRobin is like Lookup, but rotates through all the addresses of host on successive calls.
+// The rotation restarts when the host table changes.
+func (h *staticHosts) LookupRoundRobin(host string) net.IP {
+	if h == nil || host == "" {
+		return nil
+	}
+
+	t := h.load()
+	ips := h.lookupAll(t, host)
+	if len(ips) == 0 {
+		return nil
+	}
+	n := t.index.next(h.rotationKey(t, host))
+	return ips[n%uint32(len(ips))]
+}
+
+// rotationKey returns the key of the round-robin counter of host, which is the canonical hostname of its entry,
+// so that the aliases and the names with a port share the rotation of the hostname.
+// The names matched by a wildcard, a pattern or the catch-all entry share the rotation of the matched name,
+// so that the counters do not grow with the queried names.
+func (h *staticHosts) rotationKey(t *table, host string) string {
+	v, ok := h.lookupHost(t, host)
+	switch {
+	case !ok:
+		return h.key(trimPort(host))
+	case strings.HasPrefix(v.Hostname, "*") || isPattern(v.Hostname):
+		return v.Hostname // never a valid name, so it is not shared with an exact name
+	default:
+		return h.key(v.Hostname)
+	}
+}
+
+// LookupFamily is like LookupAll, but only returns the addresses of the network family,
+// which must be "ip", "ip4" or "ip6", like net.Resolver.LookupIP.
+func (h *staticHosts) LookupFamily(host, network string) ([]net.IP, error) {
+	switch network {
+	case "ip", "ip4", "ip6":
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+
+	var ips []net.IP
+	for _, ip := range h.LookupAll(host) {
+		if matchNetwork(network, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// LookupHost is like LookupAll, but returns the addresses in their string forms like net.Resolver.LookupHost.
+// A *net.DNSError with IsNotFound set is returned if host is not found.
+func (h *staticHosts) LookupHost(host string) (addrs []string, err error) {
+	ips := h.LookupAll(host)
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	addrs = make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, ip.String())
+	}
+	return addrs, nil
+}
+
+// LookupWithFallback is like LookupAll, but looks up host with fallback if host is not found in the host table,
+// so that the table overrides the resolver, such as net.DefaultResolver for the hosts file then DNS.
+// A nil fallback returns no addresses and no error for the hosts not found.
+// ctx.Err() is returned if ctx is done before the lookup, ctx also bounds the lookup of fallback.
+func (h *staticHosts) LookupWithFallback(ctx context.Context, host string, fallback *net.Resolver) ([]net.IP, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	if ips := h.LookupAll(host); len(ips) > 0 || fallback == nil {
+		return ips, nil
+	}
+	addrs, err := fallback.LookupIPAddr(ctx, trimPort(host))
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// IsBlocked checks whether host is blocked, that is, the IP address it resolves to is
+// an unspecified address (0.0.0.0 or ::) or, unless disabled by WithBlockLoopback, a loopback address.
+// It returns false if host is not found.
+func (h *staticHosts) IsBlocked(host string) bool {
+	ip := h.Lookup(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsUnspecified() {
+		return true
+	}
+	return !h.options.allowLoopback && ip.IsLoopback()
+}
+
+// ReverseLookup returns the hostnames and aliases of the hosts with the given IP address.
+func (h *staticHosts) ReverseLookup(ip net.IP) (names []string) {
+	if h == nil || ip == nil {
+		return
+	}
+
+	t := h.load()
+	var now time.Time
+	for _, i := range t.index.lookupAddr(ip) {
+		host := t.hosts[i]
+		if host.expired(&now) {
+			continue
+		}
+		names = appendName(names, host.Hostname)
+		for _, alias := range host.Aliases {
+			names = appendName(names, alias)
+		}
 	}
 	return
 }
 
-// Reload parses config from r, then live reloads the hosts.
-func (h *staticHosts) Reload(r io.Reader) error {
-	var period time.Duration
+// Exists checks whether an entry matches host, by its hostname or one of its aliases.
+func (h *staticHosts) Exists(host string) bool {
+	if h == nil || host == "" {
+		return false
+	}
+
+	_, ok := h.lookupHost(h.load(), host)
+	return ok
+}
+
+// HasIP checks whether an entry has the IP address ip.
+func (h *staticHosts) HasIP(ip net.IP) bool {
+	if h == nil || ip == nil {
+		return false
+	}
+
+	t := h.load()
+	var now time.Time
+	for _, i := range t.index.lookupAddr(ip) {
+		if !t.hosts[i].expired(&now) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAll returns a copy of all the entries of the host table.
+func (h *staticHosts) GetAll() []Host {
+	return cloneHosts(h.load().hosts)
+}
+
+// Filter returns a copy of the entries of the host table for which pred returns true, in order.
+// The entries are the ones of the table when Filter is called.
+func (h *staticHosts) Filter(pred func(Host) bool) []Host {
 	var hosts []Host
+	for _, host := range h.load().hosts {
+		if host = host.clone(); pred(host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
 
-	if r == nil || h.Stopped() {
-		return nil
+// Range calls f with a copy of each entry of the host table in order, until f returns false.
+// The entries are the ones of the table when Range is called, f is free to modify the table.
+func (h *staticHosts) Range(f func(Host) bool) {
+	for _, host := range h.load().hosts {
+		if !f(host.clone()) {
+			return
+		}
+	}
+}
+
+// Count returns the number of entries in the host table.
+func (h *staticHosts) Count() int {
+	return len(h.load().hosts)
+}
+
+// Clear removes all the entries, including the networks, from the host table.
+func (h *staticHosts) Clear() {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.setTable(newTable(nil, nil, h.key))
+}
+
+// Add appends host to the host table.
+// An error is returned if host is not valid, see Host.Validate.
+// The names of host already in the table are resolved by the conflict strategy:
+// with ConflictFirstWins the existing entries are kept and take precedence over host,
+// with ConflictLastWins the existing definitions of the names are overridden by host,
+// and with ConflictError a *DuplicateError is returned and the table is left unchanged.
+func (h *staticHosts) Add(host Host) error {
+	if err := host.validate(h.options.underscore); err != nil {
+		return err
 	}
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-		ss := splitLine(line)
-		if len(ss) < 2 {
-			continue // invalid lines are ignored
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	hosts := h.load().hosts
+	switch h.options.conflict {
+	case ConflictLastWins:
+		hosts = overrideHosts(hosts, host, h.key)
+	case ConflictError:
+		if err := checkDuplicate(hosts, host, h.key); err != nil {
+			return err
 		}
+	}
 
-		switch ss[0] {
-		case "reload": // reload option
-			period, _ = time.ParseDuration(ss[1])
-		default:
-			ip := net.ParseIP(ss[0])
-			if ip == nil {
-				break // invalid IP addresses are ignored
-			}
-			host := Host{
-				IP:       ip,
-				Hostname: ss[1],
+	v := make([]Host, 0, len(hosts)+1)
+	v = append(v, hosts...)
+	v = append(v, host.withExpires(time.Now()))
+	h.setHosts(v)
+
+	return nil
+}
+
+// Set appends host to the host table, replacing the existing entries with the same hostname.
+// An error is returned for an invalid host like Add, leaving the host table unchanged.
+func (h *staticHosts) Set(host Host) error {
+	if err := host.validate(h.options.underscore); err != nil {
+		return err
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	hosts := removeHosts(h.load().hosts, host.Hostname, h.key)
+	hosts = append(hosts, host.withExpires(time.Now()))
+	h.setHosts(hosts)
+
+	return nil
+}
+
+// Remove removes the entries with the given hostname, along with their aliases, from the host table.
+func (h *staticHosts) Remove(hostname string) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.setHosts(removeHosts(h.load().hosts, hostname, h.key))
+}
+
+// setHosts replaces the entries of the host table and rebuilds the index, h.mux must be held.
+// The networks, the aliases of the cname option and the negated names are kept.
+func (h *staticHosts) setHosts(hosts []Host) {
+	h.setTable(h.load().withHosts(hosts, h.key))
+}
+
+// setTable replaces the host table with t, h.mux must be held.
+// The table is changed since the last reload.
+func (h *staticHosts) setTable(t *table) {
+	h.table.Store(t)
+	h.modified = time.Now()
+	h.checksum = nil
+}
+
+// load returns the current host table, which must not be modified.
+func (h *staticHosts) load() *table {
+	return h.table.Load().(*table)
+}
+
+// Reload parses config from r, then live reloads the hosts.
+// Invalid lines are ignored, they can be retrieved by Errors after the reload.
+// If the config is the same as the one of the last reload, and the table is not changed since,
+// or if its entries are the same as the ones of the table, the OnReload callbacks are not called, see Changed.
+// The config of an entry with a TTL is always reloaded to renew the TTL.
+// Relative paths of the include option are resolved against the working directory.
+// It is safe to call Reload concurrently, the reloads are serialized.
+func (h *staticHosts) Reload(r io.Reader) error {
+	if r == nil {
+		return nil
+	}
+	return h.reload(func(p *parser) error {
+		return p.parse(r)
+	})
+}
+
+// ReloadFile is like Reload, but parses config from the file at path.
+// Relative paths of the include option are resolved against the directory of the file.
+func (h *staticHosts) ReloadFile(path string) error {
+	return h.reload(func(p *parser) error {
+		return p.parseFile(path)
+	})
+}
+
+// ReloadDir is like ReloadFile, but parses the files in dir whose names match glob, in the lexical order of the names,
+// such as the fragments in /etc/hosts.d matching *.hosts.
+// The names defined in more than one file are resolved by the conflict strategy like the ones of a single file.
+func (h *staticHosts) ReloadDir(dir, glob string) error {
+	if _, err := filepath.Match(glob, ""); err != nil {
+		return err
+	}
+	return h.reload(func(p *parser) error {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, fi := range files {
+			if ok, _ := filepath.Match(glob, fi.Name()); !ok || fi.IsDir() {
+				continue
 			}
-			if len(ss) > 2 {
-				host.Aliases = ss[2:]
+			if err := p.parseFile(filepath.Join(dir, fi.Name())); err != nil {
+				return err
 			}
-			hosts = append(hosts, host)
 		}
+		return nil
+	})
+}
+
+// reload parses config with parse, then applies the result to the host table.
+// The reloads are serialized by h.reloadMux, so that a reload is never overwritten by an earlier one.
+func (h *staticHosts) reload(parse func(p *parser) error) error {
+	if h.Stopped() {
+		return nil
+	}
+
+	h.reloadMux.Lock()
+	p := h.newParser()
+	err := parse(p)
+	changed := false
+	if err == nil {
+		changed = h.apply(p)
 	}
-	if err := scanner.Err(); err != nil {
+	h.reloadMux.Unlock()
+
+	if err != nil {
+		h.mux.Lock()
+		h.changed = false
+		h.mux.Unlock()
+		h.reloadFailed(err)
 		return err
 	}
+	atomic.AddUint64(&h.counters.reloads, 1)
+	h.setReady()
+	count := h.Count()
+	for i := range p.errs {
+		h.logf("hosts: skip %v", &p.errs[i])
+	}
+	for i := range p.dups {
+		h.logf("hosts: %v", &p.dups[i])
+	}
+	h.logf("hosts: reloaded %d entries", count)
+	if changed {
+		h.notify(count)
+	}
 
+	return nil
+}
+
+// Validate parses config from r like Reload, but without applying it to the host table.
+// The invalid lines are returned as ParseErrors.
+func (h *staticHosts) Validate(r io.Reader) error {
+	if r == nil {
+		return nil
+	}
+	p := h.newParser()
+	if err := p.parse(r); err != nil {
+		return err
+	}
+	if len(p.errs) > 0 {
+		return ParseErrors(p.errs)
+	}
+	return nil
+}
+
+// apply replaces the host table with the result of p merged with the pinned entries,
+// it returns false if the checksum of the config is the same as the last one and the table is left as is,
+// or if the entries of the new table are the same as the current ones, such as for a config only edited in white space.
+func (h *staticHosts) apply(p *parser) bool {
+	checksum := p.checksum()
 	h.mux.Lock()
-	h.period = period
-	h.hosts = hosts
+	unchanged := checksum != nil && bytes.Equal(checksum, h.checksum)
+	if unchanged {
+		h.changed = false
+	}
 	h.mux.Unlock()
+	if unchanged {
+		return false
+	}
 
-	return nil
+	p.finish()
+	sortNets(p.nets)
+
+	h.mux.Lock()
+	t := &table{hosts: p.hosts, index: p.index, nets: p.nets, cnames: p.cnames, negated: p.negated}
+	if len(h.pinned) > 0 {
+		t = t.withHosts(mergePinned(p.hosts, h.pinned, h.key), h.key)
+	}
+	changed := !sameTable(h.load(), t)
+	h.table.Store(t)
+	if changed {
+		h.modified = time.Now()
+	}
+	h.changed = changed
+	h.checksum = checksum
+	if p.reload {
+		h.period, h.configPeriod = p.period, true
+	} else if h.configPeriod {
+		// the reload option is removed from the config.
+		h.period, h.configPeriod = 0, false
+	}
+	h.errs = p.errs
+	h.mux.Unlock()
+
+	return changed
+}
+
+// Changed reports whether the last reload changed the host table.
+// It is false after a failed reload, or a reload whose parsed entries are the same as the ones of the table,
+// so that the callers can skip the side effects of an identical reload.
+func (h *staticHosts) Changed() bool {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	return h.changed
+}
+
+// notify reports the change of the host table of count entries to the subscribers and the callbacks.
+func (h *staticHosts) notify(count int) {
+	h.mux.RLock()
+	callbacks := h.callbacks
+	h.mux.RUnlock()
+
+	h.publish()
+
+	for _, f := range callbacks {
+		f(count)
+	}
+}
+
+// LastModified returns the time the host table was last changed, by a reload or by a modification such as Add,
+// or the zero time if it is never changed since NewHosts.
+// A reload skipped for a config unchanged since the last reload does not change the time.
+func (h *staticHosts) LastModified() time.Time {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	return h.modified
+}
+
+// Errors returns the invalid lines found by the last reload.
+func (h *staticHosts) Errors() []ParseError {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	errs := make([]ParseError, len(h.errs))
+	copy(errs, h.errs)
+	return errs
 }
 
 // Period returns the reload period
@@ -133,41 +755,144 @@ func (h *staticHosts) Period() time.Duration {
 	return h.period
 }
 
-// Stop stops reloading.
+// SetPeriod sets the reload period of Run, a zero or negative period disables the automatic reloading.
+// The waiting reloader is woken to wait for the new period from the last reload,
+// the period is replaced by the reload option of the next reloaded config if it has one,
+// and kept by the configs without one.
+func (h *staticHosts) SetPeriod(d time.Duration) {
+	h.mux.Lock()
+	h.period, h.configPeriod = d, false
+	h.mux.Unlock()
+
+	select {
+	case h.periodChanged <- struct{}{}:
+	default:
+	}
+}
+
+// Stop stops reloading, the goroutines started by Run, Watch and ReloadOnSignal exit,
+// and the channels returned by Subscribe are closed.
+// A reload in progress is not interrupted, Wait waits for the goroutines to exit.
+// The reloader can be started again by Start.
 func (h *staticHosts) Stop() {
+	h.stopMux.Lock()
 	select {
 	case <-h.stopped:
 	default:
 		close(h.stopped)
 	}
+	h.stopMux.Unlock()
+
+	h.subs.close()
+}
+
+// Wait blocks until the goroutines started by Run, Start, Watch, WatchDir, Feed and ReloadOnSignal exit,
+// so that after Stop then Wait no reload is in progress, and the watched files are no longer read.
+// Wait must not be called concurrently with the methods starting the goroutines.
+func (h *staticHosts) Wait() {
+	h.goroutines.Wait()
+}
+
+// spawn starts a goroutine of the reloader running f, which is waited for by Wait.
+func (h *staticHosts) spawn(f func()) {
+	h.goroutines.Add(1)
+	go func() {
+		defer h.goroutines.Done()
+		f()
+	}()
+}
+
+// Start starts the stopped reloader again, and resumes the periodic reloading of the source of the last Run.
+// The goroutines of Watch and ReloadOnSignal are not resumed, they have to be started again.
+// Start does nothing if the reloader is not stopped.
+func (h *staticHosts) Start() {
+	h.stopMux.Lock()
+	defer h.stopMux.Unlock()
+
+	select {
+	case <-h.stopped:
+	default:
+		return
+	}
+	h.stopped = make(chan struct{})
+	if source, done := h.source, h.stopped; source != nil {
+		h.spawn(func() { h.run(source, done) })
+	}
+}
+
+// Restart stops the reloader then starts it again,
+// so that the source of the last Run is reloaded immediately.
+func (h *staticHosts) Restart() {
+	h.Stop()
+	h.Start()
 }
 
 // Stopped checks whether the reloader is stopped.
 func (h *staticHosts) Stopped() bool {
 	select {
-	case <-h.stopped:
+	case <-h.done():
 		return true
 	default:
 		return false
 	}
 }
 
-// splitLine splits a line text by white space, mainly used by config parser.
-func splitLine(line string) []string {
-	if line == "" {
-		return nil
+// done returns the channel closed when the reloader is stopped.
+// The channel is replaced when the reloader is started again,
+// so the goroutines of the reloader must get it once when they start.
+func (h *staticHosts) done() <-chan struct{} {
+	h.stopMux.Lock()
+	defer h.stopMux.Unlock()
+
+	return h.stopped
+}
+
+// removeHosts returns a copy of hosts without the entries with the given hostname.
+func removeHosts(hosts []Host, hostname string, key func(string) string) []Host {
+	hostname = key(hostname)
+	v := make([]Host, 0, len(hosts)+1)
+	for _, host := range hosts {
+		if key(host.Hostname) != hostname {
+			v = append(v, host)
+		}
 	}
-	if n := strings.IndexByte(line, '#'); n >= 0 {
-		line = line[:n]
+	return v
+}
+
+// appendIP appends a copy of ip to ips if it is not already present,
+// so that the returned addresses do not share memory with the host table.
+func appendIP(ips []net.IP, ip net.IP) []net.IP {
+	if containsIP(ips, ip) {
+		return ips
 	}
-	line = strings.Replace(line, "\t", " ", -1)
-	line = strings.TrimSpace(line)
+	return append(ips, copyIP(ip))
+}
 
-	var ss []string
-	for _, s := range strings.Split(line, " ") {
-		if s = strings.TrimSpace(s); s != "" {
-			ss = append(ss, s)
+// copyIP returns a copy of ip.
+func copyIP(ip net.IP) net.IP {
+	if ip == nil {
+		return nil
+	}
+	return append(net.IP(nil), ip...)
+}
+
+// appendName appends name to names if it is not empty and not already present.
+func appendName(names []string, name string) []string {
+	if name == "" {
+		return names
+	}
+	for _, v := range names {
+		if v == name {
+			return names
 		}
 	}
-	return ss
+	return append(names, name)
+}
+
+// lessHost orders the hosts by hostname then IP, the default order of WithSort.
+func lessHost(a, b Host) bool {
+	if a.Hostname != b.Hostname {
+		return a.Hostname < b.Hostname
+	}
+	return bytes.Compare(a.IP.To16(), b.IP.To16()) < 0
 }
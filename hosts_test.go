@@ -1,8 +1,15 @@
 package hosts
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
 	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var hostsLookupTests = []struct {
@@ -21,14 +28,866 @@ var hostsLookupTests = []struct {
 	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, "example", nil},
 	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example", "examples")}, "example", net.IPv4(192, 168, 1, 1)},
 	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example", "examples")}, "examples", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, "Example.COM", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, "example.com:80", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, "example.com:", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, "example.com:80:80", nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "::1")}, "::1", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "::1")}, "[::1]:80", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "::1")}, "[::1]", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "Example.com", "EXAMPLE")}, "example", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, "example.com.", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com.")}, "example.com", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, ".", nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), ".")}, "example.com", nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "*.example.com")}, "www.example.com.", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "münchen.de")}, "xn--mnchen-3ya.de", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "xn--mnchen-3ya.de")}, "MÜNCHEN.de", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "*.münchen.de")}, "www.xn--mnchen-3ya.de", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "*.example.com")}, "api.example.com", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "*.example.com")}, "a.b.example.com", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "*.example.com")}, "example.com", nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "*.example.com")}, "*.example.com", nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "*.example.com")}, "api.example.org", nil},
+	{[]Host{
+		NewHost(net.IPv4(192, 168, 1, 1), "*.example.com"),
+		NewHost(net.IPv4(192, 168, 1, 2), "api.example.com"),
+	}, "api.example.com", net.IPv4(192, 168, 1, 2)},
+	{[]Host{
+		NewHost(net.IPv4(192, 168, 1, 1), "*.example.com"),
+		NewHost(net.IPv4(192, 168, 1, 2), "*.api.example.com"),
+	}, "v1.api.example.com", net.IPv4(192, 168, 1, 2)},
+	{[]Host{
+		NewHost(net.IPv4(192, 168, 1, 1), "*.example.com"),
+		NewHost(net.IPv4(192, 168, 1, 2), "*.api.example.com"),
+	}, "www.example.com", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), `re:ads\d+\.example\.com`)}, "ads1.example.com", net.IPv4(0, 0, 0, 0)},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), `re:ads\d+\.example\.com`)}, "ADS12.example.com:80", net.IPv4(0, 0, 0, 0)},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), `re:ads\d+\.example\.com`)}, "www.ads1.example.com", nil},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), `re:ads\d+\.example\.com`)}, "ads.example.com", nil},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), `re:[`)}, "[", nil},
+	{[]Host{
+		NewHost(net.IPv4(0, 0, 0, 0), `re:.*\.example\.com`),
+		NewHost(net.IPv4(192, 168, 1, 1), "*.example.com"),
+		NewHost(net.IPv4(192, 168, 1, 2), "ads1.example.com"),
+	}, "ads1.example.com", net.IPv4(192, 168, 1, 2)},
+	{[]Host{
+		NewHost(net.IPv4(0, 0, 0, 0), `re:.*\.example\.com`),
+		NewHost(net.IPv4(192, 168, 1, 1), "*.example.com"),
+	}, "ads1.example.com", net.IPv4(192, 168, 1, 1)},
+	{[]Host{
+		NewHost(net.IPv4(192, 168, 1, 1), "example.org", `re:ads\d\.example\.com`),
+		NewHost(net.IPv4(192, 168, 1, 2), `re:ads.*`),
+	}, "ads1.example.com", net.IPv4(192, 168, 1, 1)},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), "glob:*.cdn.*.example.com")}, "img.cdn.eu.example.com", net.IPv4(0, 0, 0, 0)},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), "glob:*.cdn.*.example.com")}, "IMG.CDN.EU.example.com:443", net.IPv4(0, 0, 0, 0)},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), "glob:*.cdn.*.example.com")}, "img.cdn.eu.west.example.com", nil},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), "glob:*.cdn.*.example.com")}, "cdn.eu.example.com", nil},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), "glob:ads?.example.[ct]om")}, "ads1.example.com", net.IPv4(0, 0, 0, 0)},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), "glob:ads?.example.[ct]om")}, "ads12.example.com", nil},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), "glob:ads?.example.com")}, "ads..example.com", nil},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), "glob:ADS?.Example.COM")}, "ads1.example.com", net.IPv4(0, 0, 0, 0)},
+	{[]Host{NewHost(net.IPv4(0, 0, 0, 0), `re:ADS\d+\.Example\.com`)}, "Ads1.example.com", net.IPv4(0, 0, 0, 0)},
+	{[]Host{
+		NewHost(net.IPv4(0, 0, 0, 0), "glob:ads*.example.com"),
+		NewHost(net.IPv4(192, 168, 1, 1), "ads1.example.com"),
+	}, "ads1.example.com", net.IPv4(192, 168, 1, 1)},
+	{[]Host{
+		NewHost(net.IPv4(0, 0, 0, 0), "glob:ads*.example.com"),
+		NewHost(net.IPv4(192, 168, 1, 1), `re:ads\d\.example\.com`),
+	}, "ads1.example.com", net.IPv4(0, 0, 0, 0)},
 }
 
 func TestHostsLookup(t *testing.T) {
 	for i, tc := range hostsLookupTests {
-		hosts := NewHosts(tc.hosts...)
+		hosts := NewHosts(WithInitialHosts(tc.hosts...))
 		ip := hosts.Lookup(tc.host)
 		if !ip.Equal(tc.ip) {
 			t.Errorf("#%d test failed: lookup should be %s, got %s", i, tc.ip, ip)
 		}
 	}
 }
+
+func TestHostsLookupPatternCaseSensitive(t *testing.T) {
+	hosts := NewHosts(
+		WithInitialHosts(
+			NewHost(net.IPv4(0, 0, 0, 0), "glob:ADS?.example.com"),
+			NewHost(net.IPv4(0, 0, 0, 1), `re:Cdn\d\.example\.com`),
+		),
+		WithCaseInsensitive(false),
+	)
+	var tests = []struct {
+		host string
+		ip   net.IP
+	}{
+		{"ADS1.example.com", net.IPv4(0, 0, 0, 0)},
+		{"ads1.example.com", nil},
+		{"Cdn1.example.com", net.IPv4(0, 0, 0, 1)},
+		{"cdn1.example.com", nil},
+	}
+	for i, tc := range tests {
+		if ip := hosts.Lookup(tc.host); !ip.Equal(tc.ip) {
+			t.Errorf("#%d test failed: lookup of %s should be %s, got %s", i, tc.host, tc.ip, ip)
+		}
+	}
+}
+
+var hostsLookupAllTests = []struct {
+	hosts []Host
+	host  string
+	ips   []net.IP
+}{
+	{nil, "example.com", nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, "example", nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, "example.com", []net.IP{net.IPv4(192, 168, 1, 1)}},
+	{[]Host{
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		NewHost(net.ParseIP("::1"), "example.com"),
+		NewHost(net.IPv4(192, 168, 1, 2), "example.org", "example.com"),
+	}, "example.com", []net.IP{net.IPv4(192, 168, 1, 1), net.ParseIP("::1"), net.IPv4(192, 168, 1, 2)}},
+	{[]Host{
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"),
+		NewHost(net.IPv4(192, 168, 1, 2), "example"),
+	}, "example", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}},
+}
+
+func TestHostsLookupAll(t *testing.T) {
+	for i, tc := range hostsLookupAllTests {
+		hosts := NewHosts(WithInitialHosts(tc.hosts...))
+		ips := hosts.LookupAll(tc.host)
+		if len(ips) != len(tc.ips) {
+			t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+			continue
+		}
+		for j := range ips {
+			if !ips[j].Equal(tc.ips[j]) {
+				t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+				break
+			}
+		}
+	}
+}
+
+var hostsReloadTests = []struct {
+	r    string
+	host string
+	ip   net.IP
+}{
+	{"", "example.com", nil},
+	{"192.168.1.1 example.com", "example.com", net.IPv4(192, 168, 1, 1)},
+	{"192.168.1.1 example.com", "example", nil},
+	{"# 192.168.1.1 example.com", "example.com", nil},
+	{"192.168.1.1 example.com example examples", "examples", net.IPv4(192, 168, 1, 1)},
+	{"foo example.com\n192.168.1.2 example.com", "example.com", net.IPv4(192, 168, 1, 2)},
+	{"192.168.1.1\texample.com\n192.168.1.2 example.com", "example.com", net.IPv4(192, 168, 1, 1)},
+	{"192.168.1.1 Example.com Example", "EXAMPLE", net.IPv4(192, 168, 1, 1)},
+	{"192.168.1.1 example.com *.example.com", "www.example.com", net.IPv4(192, 168, 1, 1)},
+	{"192.168.1.1 münchen.de", "xn--mnchen-3ya.de", net.IPv4(192, 168, 1, 1)},
+	{"example.com 192.168.1.1 example", "example", net.IPv4(192, 168, 1, 1)},
+	{"example.com 192.168.1.1 192.168.1.2 example", "example", net.IPv4(192, 168, 1, 1)},
+	{"192.168.1.1 192.168.1.2", "192.168.1.2", nil},
+	{"192.168.1.1 example.com\n192.168.1.2 example.com -münchen.de", "example.com", net.IPv4(192, 168, 1, 1)},
+	{"192.168.1.2 example.com -münchen.de\n192.168.1.1 example.com", "example.com", net.IPv4(192, 168, 1, 1)},
+	{"0.0.0.0 re:ads[0-9]+\\.example\\.com", "ads1.example.com", net.IPv4(0, 0, 0, 0)},
+	{"0.0.0.0 re:ads[0-9+\\.example\\.com\n192.168.1.1 ads1.example.com", "ads1.example.com", net.IPv4(192, 168, 1, 1)},
+}
+
+func TestHostsReload(t *testing.T) {
+	for i, tc := range hostsReloadTests {
+		hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(10, 0, 0, 1), "example.com"))).(*staticHosts)
+		if err := hosts.Reload(strings.NewReader(tc.r)); err != nil {
+			t.Error(err)
+		}
+		ip := hosts.Lookup(tc.host)
+		if !ip.Equal(tc.ip) {
+			t.Errorf("#%d test failed: lookup should be %s, got %s", i, tc.ip, ip)
+		}
+	}
+}
+
+var hostsReverseLookupTests = []struct {
+	hosts []Host
+	ip    net.IP
+	names []string
+}{
+	{nil, net.IPv4(192, 168, 1, 1), nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, nil, nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, net.IPv4(192, 168, 1, 2), nil},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example")}, net.IPv4(192, 168, 1, 1), []string{"example.com", "example"}},
+	{[]Host{NewHost(net.IPv4(192, 168, 1, 1), "example.com")}, net.ParseIP("::ffff:192.168.1.1"), []string{"example.com"}},
+	{[]Host{NewHost(net.ParseIP("::ffff:192.168.1.1"), "example.com")}, net.IP{192, 168, 1, 1}, []string{"example.com"}},
+	{[]Host{
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"),
+		NewHost(net.IPv4(192, 168, 1, 2), "example.org"),
+		NewHost(net.IPv4(192, 168, 1, 1), "example.net", "example"),
+	}, net.IPv4(192, 168, 1, 1), []string{"example.com", "example", "example.net"}},
+}
+
+func TestHostsReverseLookup(t *testing.T) {
+	for i, tc := range hostsReverseLookupTests {
+		hosts := NewHosts(WithInitialHosts(tc.hosts...))
+		names := hosts.ReverseLookup(tc.ip)
+		if strings.Join(names, ",") != strings.Join(tc.names, ",") {
+			t.Errorf("#%d test failed: reverse lookup should be %v, got %v", i, tc.names, names)
+		}
+	}
+}
+
+func TestHostsLookupContext(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))).(*staticHosts)
+
+	ip, err := hosts.LookupContext(context.Background(), "example.com")
+	if err != nil || !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s, %v", net.IPv4(192, 168, 1, 1), ip, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ip, err = hosts.LookupContext(ctx, "example.com")
+	if err != context.Canceled || ip != nil {
+		t.Errorf("lookup should be canceled, got %s, %v", ip, err)
+	}
+}
+
+func TestHostsBatchLookup(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"),
+		NewHost(net.IPv4(192, 168, 1, 2), "*.example.org"),
+	)).(*staticHosts)
+
+	m := hosts.BatchLookup([]string{"example", "www.example.org", "example.net", "", "EXAMPLE.com"})
+	expected := map[string]net.IP{
+		"example":         net.IPv4(192, 168, 1, 1),
+		"www.example.org": net.IPv4(192, 168, 1, 2),
+		"EXAMPLE.com":     net.IPv4(192, 168, 1, 1),
+	}
+	if len(m) != len(expected) {
+		t.Errorf("batch lookup should be %v, got %v", expected, m)
+	}
+	for host, ip := range expected {
+		if v, ok := m[host]; !ok || !v.Equal(ip) {
+			t.Errorf("lookup of %s should be %s, got %s", host, ip, v)
+		}
+	}
+	if _, ok := m["example.net"]; ok {
+		t.Errorf("example.net should be absent")
+	}
+}
+
+func TestHostsLookupLockFree(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))).(*staticHosts)
+
+	hosts.mux.Lock()
+	defer hosts.mux.Unlock()
+
+	done := make(chan net.IP)
+	go func() {
+		done <- hosts.Lookup("example.com")
+	}()
+	select {
+	case ip := <-done:
+		if !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+			t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lookup should not wait for the lock")
+	}
+}
+
+func TestHostsCaseInsensitive(t *testing.T) {
+	host := NewHost(net.IPv4(192, 168, 1, 1), "Example.com")
+
+	hosts := NewHosts(WithInitialHosts(host))
+	if ip := hosts.Lookup("EXAMPLE.COM"); !ip.Equal(host.IP) {
+		t.Errorf("lookup should be %s, got %s", host.IP, ip)
+	}
+
+	hosts = NewHosts(WithInitialHosts(host), WithCaseInsensitive(false))
+	if ip := hosts.Lookup("EXAMPLE.COM"); ip != nil {
+		t.Errorf("lookup should be nil, got %s", ip)
+	}
+	if ip := hosts.Lookup("Example.com"); !ip.Equal(host.IP) {
+		t.Errorf("lookup should be %s, got %s", host.IP, ip)
+	}
+}
+
+func TestHostsAddRemove(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"))).(*staticHosts)
+
+	hosts.Add(NewHost(net.IPv4(192, 168, 1, 2), "example.com"))
+	hosts.Add(NewHost(net.IPv4(192, 168, 1, 3), "example.org", "org"))
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+	if ips := hosts.LookupAll("example.com"); len(ips) != 2 {
+		t.Errorf("lookup should have 2 addresses, got %v", ips)
+	}
+	if ip := hosts.Lookup("org"); !ip.Equal(net.IPv4(192, 168, 1, 3)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 3), ip)
+	}
+
+	hosts.Set(NewHost(net.IPv4(192, 168, 1, 4), "Example.com"))
+	if ips := hosts.LookupAll("example.com"); len(ips) != 1 || !ips[0].Equal(net.IPv4(192, 168, 1, 4)) {
+		t.Errorf("lookup should be %s, got %v", net.IPv4(192, 168, 1, 4), ips)
+	}
+	if ip := hosts.Lookup("example"); ip != nil {
+		t.Errorf("lookup should be %v, got %s", nil, ip)
+	}
+
+	hosts.Remove("example.org")
+	if ip := hosts.Lookup("example.org"); ip != nil {
+		t.Errorf("lookup should be %v, got %s", nil, ip)
+	}
+	if ip := hosts.Lookup("org"); ip != nil {
+		t.Errorf("lookup should be %v, got %s", nil, ip)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 4)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 4), ip)
+	}
+}
+
+func TestHostsAddConcurrent(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			hosts.Add(NewHost(net.IPv4(192, 168, 1, byte(i)), "example.com"))
+		}(i)
+		go func() {
+			defer wg.Done()
+			hosts.Lookup("example.com")
+		}()
+	}
+	wg.Wait()
+
+	if ips := hosts.LookupAll("example.com"); len(ips) != 100 {
+		t.Errorf("lookup should have 100 addresses, got %d", len(ips))
+	}
+}
+
+func TestHostsLookupCopy(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		NewHost(net.ParseIP("fe80::1"), "example.com"),
+	)).(*staticHosts)
+
+	ip := hosts.Lookup("example.com")
+	ip[len(ip)-1] = 2
+	ips := hosts.LookupAll("example.com")
+	for _, ip := range ips {
+		ip[len(ip)-1] = 3
+	}
+	for _, addr := range hosts.LookupIPAddr("example.com") {
+		addr.IP[len(addr.IP)-1] = 4
+	}
+	if ip := hosts.BatchLookup([]string{"example.com"})["example.com"]; ip != nil {
+		ip[len(ip)-1] = 5
+	}
+
+	ips = hosts.LookupAll("example.com")
+	expected := []net.IP{net.IPv4(192, 168, 1, 1), net.ParseIP("fe80::1")}
+	if !equalIPs(ips, expected) {
+		t.Errorf("lookup should be %v, got %v", expected, ips)
+	}
+	if names := hosts.ReverseLookup(net.IPv4(192, 168, 1, 1)); len(names) != 1 {
+		t.Errorf("reverse lookup should be [example.com], got %v", names)
+	}
+}
+
+func TestHostsGetAll(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"),
+		NewHost(net.IPv4(192, 168, 1, 2), "example.org"),
+	)).(*staticHosts)
+
+	all := hosts.GetAll()
+	if len(all) != 2 || all[0].Hostname != "example.com" || all[1].Hostname != "example.org" {
+		t.Fatalf("entries should be example.com and example.org, got %v", all)
+	}
+	all[0].IP[len(all[0].IP)-1] = 2
+	all[0].Aliases[0] = "foo"
+	if ip := hosts.Lookup("example"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+
+	var names []string
+	hosts.Range(func(host Host) bool {
+		names = append(names, host.Hostname)
+		return false
+	})
+	if len(names) != 1 || names[0] != "example.com" {
+		t.Errorf("range should stop after example.com, got %v", names)
+	}
+}
+
+func TestHostsCountClear(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example", "examples"),
+		NewHost(net.IPv4(192, 168, 1, 2), "example.org"),
+	)).(*staticHosts)
+	if n := hosts.Count(); n != 2 {
+		t.Errorf("count should be %d, got %d", 2, n)
+	}
+
+	hosts.Clear()
+	if n := hosts.Count(); n != 0 {
+		t.Errorf("count should be %d, got %d", 0, n)
+	}
+	if ip := hosts.Lookup("example.com"); ip != nil {
+		t.Errorf("lookup should be %v, got %s", nil, ip)
+	}
+	if names := hosts.ReverseLookup(net.IPv4(192, 168, 1, 1)); names != nil {
+		t.Errorf("reverse lookup should be %v, got %v", nil, names)
+	}
+}
+
+var hostsReloadMultiIPTests = []struct {
+	r    string
+	host string
+	ips  []net.IP
+}{
+	{"192.168.1.1 example.com", "example.com", []net.IP{net.IPv4(192, 168, 1, 1)}},
+	{"example.com 192.168.1.1 192.168.1.2 ::1", "example.com", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2), net.ParseIP("::1")}},
+	{"192.168.1.1 example.com 192.168.1.2 example", "example", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}},
+	{"example.com 192.168.1.1 192.168.1.1\n192.168.1.2 example.com", "example.com", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}},
+}
+
+func TestHostsReloadMultiIP(t *testing.T) {
+	for i, tc := range hostsReloadMultiIPTests {
+		hosts := NewHosts().(*staticHosts)
+		if err := hosts.Reload(strings.NewReader(tc.r)); err != nil {
+			t.Error(err)
+		}
+		ips := hosts.LookupAll(tc.host)
+		if len(ips) != len(tc.ips) {
+			t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+			continue
+		}
+		for j := range ips {
+			if !ips[j].Equal(tc.ips[j]) {
+				t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+				break
+			}
+		}
+	}
+}
+
+var hostsIsBlockedTests = []struct {
+	host          string
+	blockLoopback bool
+	blocked       bool
+}{
+	{"example.com", true, false},
+	{"ads.example.com", true, true},
+	{"ads.example.org", true, true},
+	{"local.example.com", true, true},
+	{"local.example.com", false, false},
+	{"ads.example.com", false, true},
+	{"missing.example.com", true, false},
+}
+
+func TestHostsIsBlocked(t *testing.T) {
+	for i, tc := range hostsIsBlockedTests {
+		hosts := NewHosts(
+			WithInitialHosts(
+				NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+				NewHost(net.IPv4zero, "ads.example.com"),
+				NewHost(net.IPv6unspecified, "ads.example.org"),
+				NewHost(net.IPv4(127, 0, 0, 1), "local.example.com"),
+			),
+			WithBlockLoopback(tc.blockLoopback),
+		).(*staticHosts)
+		if v := hosts.IsBlocked(tc.host); v != tc.blocked {
+			t.Errorf("#%d test failed: blocked should be %v, got %v", i, tc.blocked, v)
+		}
+	}
+}
+
+var hostsLookupFamilyTests = []struct {
+	network string
+	ips     []net.IP
+	err     bool
+}{
+	{"ip", []net.IP{net.IPv4(192, 168, 1, 1), net.ParseIP("2001:db8::1"), net.ParseIP("::ffff:192.168.1.2")}, false},
+	{"ip4", []net.IP{net.IPv4(192, 168, 1, 1), net.ParseIP("::ffff:192.168.1.2")}, false},
+	{"ip6", []net.IP{net.ParseIP("2001:db8::1")}, false},
+	{"tcp", nil, true},
+	{"", nil, true},
+}
+
+func TestHostsLookupHost(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		NewHost(net.ParseIP("2001:db8::1"), "example.com"),
+	)).(*staticHosts)
+
+	addrs, err := hosts.LookupHost("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(addrs, ",") != "192.168.1.1,2001:db8::1" {
+		t.Errorf("addresses should be [192.168.1.1 2001:db8::1], got %v", addrs)
+	}
+
+	addrs, err = hosts.LookupHost("example.org")
+	if e, ok := err.(*net.DNSError); !ok || !e.IsNotFound || e.Name != "example.org" || addrs != nil {
+		t.Errorf("error should be a not found *net.DNSError, got %v, %v", addrs, err)
+	}
+}
+
+func TestHostsLookupFamily(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		NewHost(net.ParseIP("2001:db8::1"), "example.com"),
+		NewHost(net.ParseIP("::ffff:192.168.1.2"), "example.com"),
+	)).(*staticHosts)
+
+	for i, tc := range hostsLookupFamilyTests {
+		ips, err := hosts.LookupFamily("example.com", tc.network)
+		if (err != nil) != tc.err {
+			t.Errorf("#%d test failed: error should be %v, got %v", i, tc.err, err)
+			continue
+		}
+		if !equalIPs(ips, tc.ips) {
+			t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+		}
+	}
+}
+
+func equalIPs(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHostsLookupRoundRobin(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		NewHost(net.IPv4(192, 168, 1, 2), "example.com", "example"),
+		NewHost(net.IPv4(192, 168, 1, 3), "example.com"),
+	)).(*staticHosts)
+
+	if ip := hosts.LookupRoundRobin("example.org"); ip != nil {
+		t.Errorf("lookup should be %v, got %s", nil, ip)
+	}
+
+	for i := 0; i < 6; i++ {
+		expected := net.IPv4(192, 168, 1, byte(i%3+1))
+		if ip := hosts.LookupRoundRobin("Example.com"); !ip.Equal(expected) {
+			t.Errorf("#%d lookup should be %s, got %s", i, expected, ip)
+		}
+	}
+	if ip := hosts.LookupRoundRobin("example"); !ip.Equal(net.IPv4(192, 168, 1, 2)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 2), ip)
+	}
+
+	hosts.LookupRoundRobin("example.com")
+	if err := hosts.Reload(strings.NewReader("example.com 192.168.1.1 192.168.1.2")); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.LookupRoundRobin("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s after reload, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+
+	// the aliases and the names with a port rotate along with the hostname.
+	if err := hosts.Reload(strings.NewReader("192.168.2.1 192.168.2.2 192.168.2.3 example.org www")); err != nil {
+		t.Fatal(err)
+	}
+	for i, host := range []string{"example.org", "www", "example.org:80", "WWW:443", "example.org"} {
+		expected := net.IPv4(192, 168, 2, byte(i%3+1))
+		if ip := hosts.LookupRoundRobin(host); !ip.Equal(expected) {
+			t.Errorf("#%d lookup of %s should be %s, got %s", i, host, expected, ip)
+		}
+	}
+}
+
+func TestHostsLookupRoundRobinWildcard(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "*.example.com"),
+		NewHost(net.IPv4(192, 168, 1, 2), "*.example.com"),
+		NewHost(net.IPv4(192, 168, 2, 1), "*"),
+		NewHost(net.IPv4(192, 168, 2, 2), "*"),
+	)).(*staticHosts)
+
+	// the names matched by the same entry share its rotation.
+	for i := 0; i < 100; i++ {
+		expected := net.IPv4(192, 168, 1, byte(i%2+1))
+		if ip := hosts.LookupRoundRobin(fmt.Sprintf("host%d.example.com", i)); !ip.Equal(expected) {
+			t.Errorf("#%d lookup should be %s, got %s", i, expected, ip)
+		}
+		expected = net.IPv4(192, 168, 2, byte(i%2+1))
+		if ip := hosts.LookupRoundRobin(fmt.Sprintf("host%d.example.org", i)); !ip.Equal(expected) {
+			t.Errorf("#%d lookup should be %s, got %s", i, expected, ip)
+		}
+	}
+
+	n := 0
+	hosts.load().index.counters.Range(func(k, v interface{}) bool {
+		n++
+		return true
+	})
+	if n != 2 {
+		t.Errorf("counters should be 2, got %d", n)
+	}
+}
+
+var hostsCanonicalTests = []struct {
+	host      string
+	canonical string
+}{
+	{"", ""},
+	{"example.com", "Example.com"},
+	{"example", "Example.com"},
+	{"EXAMPLES", "Example.com"},
+	{"example.org", ""},
+	{"www.example.net", "www.example.net"},
+	{"example.net", "example.net"},
+}
+
+func TestHostsCanonical(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "Example.com", "example", "examples"),
+		NewHost(net.IPv4(192, 168, 1, 2), "*.example.net", "example.net"),
+	)).(*staticHosts)
+
+	for i, tc := range hostsCanonicalTests {
+		if v := hosts.Canonical(tc.host); v != tc.canonical {
+			t.Errorf("#%d test failed: canonical should be %q, got %q", i, tc.canonical, v)
+		}
+	}
+}
+
+func TestHostsGetHost(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example", "examples"),
+		NewHost(net.IPv4(192, 168, 1, 2), "example.com"),
+	)).(*staticHosts)
+
+	for i, host := range []string{"example.com", "Example", "examples"} {
+		v, ok := hosts.GetHost(host)
+		if !ok {
+			t.Errorf("#%d test failed: %s should be found", i, host)
+			continue
+		}
+		if s := v.String(); s != "192.168.1.1 example.com example examples" {
+			t.Errorf("#%d test failed: host should be %q, got %q", i, "192.168.1.1 example.com example examples", s)
+		}
+	}
+	if _, ok := hosts.GetHost("example.org"); ok {
+		t.Error("example.org should not be found")
+	}
+
+	// the entry must not share memory with the host table.
+	v, _ := hosts.GetHost("example")
+	v.Aliases[0] = "changed"
+	v.IP[len(v.IP)-1] = 2
+	if ips := hosts.ReverseLookup(net.IPv4(192, 168, 1, 1)); len(ips) != 3 || ips[1] != "example" {
+		t.Errorf("names should be [example.com example examples], got %v", ips)
+	}
+}
+
+var hostsExistsTests = []struct {
+	host   string
+	exists bool
+}{
+	{"", false},
+	{"example.com", true},
+	{"EXAMPLE", true},
+	{"www.example.net", true},
+	{"example.org", false},
+	{"expired.com", false},
+}
+
+var hostsHasIPTests = []struct {
+	ip     net.IP
+	exists bool
+}{
+	{nil, false},
+	{net.IP{}, false},
+	{net.IPv4(192, 168, 1, 1), true},
+	{net.IPv4(192, 168, 1, 1).To4(), true},
+	{net.ParseIP("::ffff:192.168.1.2"), true},
+	{net.IPv4(192, 168, 1, 3), false},
+	{net.IPv4(192, 168, 1, 4), false},
+}
+
+func TestHostsExists(t *testing.T) {
+	expired := NewHost(net.IPv4(192, 168, 1, 4), "expired.com")
+	expired.TTL = time.Nanosecond
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"),
+		NewHost(net.IPv4(192, 168, 1, 2), "*.example.net"),
+		expired,
+	)).(*staticHosts)
+	time.Sleep(time.Millisecond)
+
+	for i, tc := range hostsExistsTests {
+		if v := hosts.Exists(tc.host); v != tc.exists {
+			t.Errorf("#%d test failed: %q exists should be %v, got %v", i, tc.host, tc.exists, v)
+		}
+	}
+	for i, tc := range hostsHasIPTests {
+		if v := hosts.HasIP(tc.ip); v != tc.exists {
+			t.Errorf("#%d test failed: %s exists should be %v, got %v", i, tc.ip, tc.exists, v)
+		}
+	}
+}
+
+func TestHostsFilter(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("0.0.0.0 ads.example.com\n192.168.1.1 example.com\n0.0.0.0 tracker.example.com track")); err != nil {
+		t.Fatal(err)
+	}
+
+	blocked := hosts.Filter(func(host Host) bool {
+		return host.IP.IsUnspecified()
+	})
+	if len(blocked) != 2 || blocked[0].Hostname != "ads.example.com" || blocked[1].Hostname != "tracker.example.com" {
+		t.Fatalf("entries should be ads.example.com and tracker.example.com, got %v", blocked)
+	}
+	blocked[1].Aliases[0] = "changed"
+	blocked[1].IP[0] = 10
+	if ip := hosts.Lookup("track"); !ip.Equal(net.IPv4zero) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4zero, ip)
+	}
+
+	if v := hosts.Filter(func(Host) bool { return false }); len(v) != 0 {
+		t.Errorf("entries should be empty, got %v", v)
+	}
+}
+
+var hostsCatchAllTests = []struct {
+	host string
+	ip   net.IP
+}{
+	{"example.com", net.IPv4(192, 168, 1, 1)},
+	{"www.example.org", net.IPv4(192, 168, 1, 2)},
+	{"ads1.example.net", net.IPv4(0, 0, 0, 0)},
+	{"web", net.IPv4(192, 168, 1, 1)},
+	{"unknown.example.com", net.IPv4(10, 0, 0, 2)},
+	{"unknown:80", net.IPv4(10, 0, 0, 2)},
+	{"8.8.8.8", nil},
+	{"8.8.8.8:53", nil},
+	{"[2001:db8::1]:80", nil},
+	{"9.9.9.9", nil},
+}
+
+func TestHostsCatchAll(t *testing.T) {
+	config := `
+* 10.0.0.1
+192.168.1.1 example.com
+192.168.1.2 *.example.org
+0.0.0.0 re:ads\d\.example\.net
+0.0.0.0 re:9\.9\.9\.\d *.8.8
+cname example.com web
+10.0.0.2 *
+`
+	for _, conflict := range []ConflictStrategy{ConflictFirstWins, ConflictLastWins, ConflictError} {
+		hosts := NewHosts(WithConflictStrategy(conflict)).(*staticHosts)
+		if err := hosts.Reload(strings.NewReader(config)); err != nil {
+			t.Fatal(err)
+		}
+		if errs := hosts.Errors(); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		for i, tc := range hostsCatchAllTests {
+			if ip := hosts.Lookup(tc.host); !ip.Equal(tc.ip) {
+				t.Errorf("#%d test failed: lookup of %s should be %s, got %s", i, tc.host, tc.ip, ip)
+			}
+		}
+		if ips := hosts.LookupAll("unknown"); len(ips) != 1 {
+			t.Errorf("only the last catch-all entry should be kept, got %v", ips)
+		}
+		if v := hosts.Canonical("unknown"); v != "unknown" {
+			t.Errorf("canonical should be unknown, got %q", v)
+		}
+	}
+
+	var b bytes.Buffer
+	hosts := NewHosts(WithLogger(log.New(&b, "", 0))).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); !strings.Contains(s, `duplicate name "*", first defined at line 2`) {
+		t.Errorf("log should report the duplicate catch-all entry, got %q", s)
+	}
+}
+
+var hostsAliasesTests = []struct {
+	host    string
+	aliases []string
+}{
+	{"", nil},
+	{"example.org", nil},
+	{"example.com", []string{"example", "www.example.com", "web", "api", "w3"}},
+	{"EXAMPLE", []string{"example", "www.example.com", "web", "api", "w3"}},
+	{"api", []string{"example", "www.example.com", "web", "api", "w3"}},
+	{"w3", []string{"example", "www.example.com", "web", "api", "w3"}},
+	{"example.net", nil},
+	{"a.example.net", []string{"b.example.net"}},
+}
+
+func TestHostsAliases(t *testing.T) {
+	config := `
+192.168.1.1 example.com example www.example.com
+192.168.1.2 example.com example web
+192.168.1.3 example.net
+192.168.1.4 *.example.net b.example.net
+cname example.com w3 api
+`
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range hostsAliasesTests {
+		if v := hosts.Aliases(tc.host); strings.Join(v, ",") != strings.Join(tc.aliases, ",") {
+			t.Errorf("#%d test failed: aliases of %q should be %v, got %v", i, tc.host, tc.aliases, v)
+		}
+	}
+
+	v := hosts.Aliases("example.com")
+	v[0] = "changed"
+	if v := hosts.Aliases("example.com"); v[0] != "example" {
+		t.Errorf("aliases should not share memory with the host table, got %v", v)
+	}
+}
+
+func TestHostsLastModified(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if m := hosts.LastModified(); !m.IsZero() {
+		t.Errorf("last modified should be zero, got %v", m)
+	}
+
+	config := "192.168.1.1 example.com"
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := hosts.LastModified()
+	if reloaded.IsZero() {
+		t.Fatal("last modified should be set by reload")
+	}
+
+	time.Sleep(time.Millisecond)
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if m := hosts.LastModified(); !m.Equal(reloaded) {
+		t.Errorf("last modified should be %v after an unchanged reload, got %v", reloaded, m)
+	}
+
+	hosts.Add(NewHost(net.IPv4(192, 168, 1, 2), "example.org"))
+	if m := hosts.LastModified(); !m.After(reloaded) {
+		t.Errorf("last modified should be after %v after add, got %v", reloaded, m)
+	}
+}
@@ -0,0 +1,18 @@
+module github.com/go-gost/hosts/hostsgrpc
+
+go 1.19
+
+require (
+	github.com/go-gost/hosts v0.0.0-20261014084954-101cbcc9d38f
+	google.golang.org/grpc v1.58.3
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.4.9 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	golang.org/x/net v0.12.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/text v0.11.0 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
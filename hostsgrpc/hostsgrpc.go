@@ -0,0 +1,163 @@
+// Package hostsgrpc resolves the targets of the gRPC clients with the host tables of github.com/go-gost/hosts.
+// It is a separate module, so that the hosts package does not depend on gRPC.
+//
+// The resolvers are updated through SubscribeAll of the table, which reports the changes of any address of a host,
+// and is kept across the Stop and Start of the reloader.
+package hostsgrpc
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme is the default scheme of the targets resolved by a Builder, such as hosts:///example.com:443.
+const Scheme = "hosts"
+
+// defaultPort is the port of the targets without one, like the dns resolver of gRPC.
+const defaultPort = "443"
+
+// Table is the host table resolving the targets, the Hosts returned by hosts.NewHosts implements it.
+type Table interface {
+	LookupAll(host string) []net.IP
+	SubscribeAll(host string) <-chan []net.IP
+	UnsubscribeAll(ch <-chan []net.IP)
+}
+
+// Builder is a resolver.Builder resolving the targets with LookupAll of a host table.
+// Each resolver it builds subscribes to the changes of the host of its target with SubscribeAll,
+// and updates the addresses of the target each time the addresses of the host are changed by a reload.
+// The subscription is canceled with UnsubscribeAll when the resolver is closed,
+// so that the table holds no resolvers, nor the Builder, after the clients are closed.
+type Builder struct {
+	scheme string
+	table  Table
+}
+
+// NewBuilder creates a Builder of the targets with scheme resolved by t, Scheme is used if scheme is empty.
+// The Builder is to be registered with resolver.Register or passed to grpc.WithResolvers.
+func NewBuilder(scheme string, t Table) *Builder {
+	if scheme == "" {
+		scheme = Scheme
+	}
+	return &Builder{
+		scheme: scheme,
+		table:  t,
+	}
+}
+
+// Build implements resolver.Builder.
+func (b *Builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	host, port, err := splitTarget(target.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+	r := &hostsResolver{
+		table:   b.table,
+		host:    host,
+		port:    port,
+		cc:      cc,
+		updates: b.table.SubscribeAll(host),
+	}
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	go r.watch()
+	return r, nil
+}
+
+// Scheme implements resolver.Builder.
+func (b *Builder) Scheme() string {
+	return b.scheme
+}
+
+// splitTarget splits the endpoint of a target into the host and the port, defaultPort is used if it has no port.
+func splitTarget(endpoint string) (host, port string, err error) {
+	if endpoint == "" {
+		return "", "", fmt.Errorf("hosts: missing target")
+	}
+	if ip := net.ParseIP(endpoint); ip != nil {
+		return endpoint, defaultPort, nil // an IPv6 address without brackets
+	}
+	host, port, err = net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint, defaultPort, nil
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("hosts: missing host of target %q", endpoint)
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	return host, port, nil
+}
+
+type hostsResolver struct {
+	table   Table
+	host    string
+	port    string
+	cc      resolver.ClientConn
+	updates <-chan []net.IP // the subscription of the changes of host
+	last    []resolver.Address
+	closed  bool
+	mux     sync.Mutex
+}
+
+// watch updates the addresses of the target on each change of the addresses of the host,
+// until the subscription is canceled by Close.
+func (r *hostsResolver) watch() {
+	for ips := range r.updates {
+		r.update(ips)
+	}
+}
+
+// ResolveNow implements resolver.Resolver, it looks up the host of the target and updates cc if its addresses are changed.
+func (r *hostsResolver) ResolveNow(resolver.ResolveNowOptions) {
+	r.update(r.table.LookupAll(r.host))
+}
+
+// update updates cc with the addresses ips of the host if they are changed, or reports an error if there is none.
+func (r *hostsResolver) update(ips []net.IP) {
+	addrs := make([]resolver.Address, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, resolver.Address{Addr: net.JoinHostPort(ip.String(), r.port)})
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.closed {
+		return
+	}
+	if len(addrs) == 0 {
+		r.last = nil
+		r.cc.ReportError(fmt.Errorf("hosts: %s not found", r.host))
+		return
+	}
+	if equalAddrs(addrs, r.last) {
+		return
+	}
+	r.last = addrs
+	r.cc.UpdateState(resolver.State{Addresses: addrs})
+}
+
+// Close implements resolver.Resolver, the subscription is canceled, so the target is no longer updated after the reloads.
+func (r *hostsResolver) Close() {
+	r.mux.Lock()
+	r.closed = true
+	r.mux.Unlock()
+
+	r.table.UnsubscribeAll(r.updates)
+}
+
+func equalAddrs(a, b []resolver.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Addr != b[i].Addr {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+package hostsgrpc
+
+import (
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-gost/hosts"
+	"google.golang.org/grpc/resolver"
+)
+
+type testClientConn struct {
+	resolver.ClientConn
+	states []resolver.State
+	errs   []error
+	mux    sync.Mutex
+}
+
+func (cc *testClientConn) UpdateState(s resolver.State) error {
+	cc.mux.Lock()
+	defer cc.mux.Unlock()
+
+	cc.states = append(cc.states, s)
+	return nil
+}
+
+func (cc *testClientConn) ReportError(err error) {
+	cc.mux.Lock()
+	defer cc.mux.Unlock()
+
+	cc.errs = append(cc.errs, err)
+}
+
+func (cc *testClientConn) addrs() []string {
+	cc.mux.Lock()
+	defer cc.mux.Unlock()
+
+	if len(cc.states) == 0 {
+		return nil
+	}
+	var v []string
+	for _, addr := range cc.states[len(cc.states)-1].Addresses {
+		v = append(v, addr.Addr)
+	}
+	return v
+}
+
+func (cc *testClientConn) updates() int {
+	cc.mux.Lock()
+	defer cc.mux.Unlock()
+
+	return len(cc.states)
+}
+
+func target(endpoint string) resolver.Target {
+	return resolver.Target{URL: url.URL{Scheme: Scheme, Path: "/" + endpoint}}
+}
+
+var splitTargetTests = []struct {
+	endpoint string
+	host     string
+	port     string
+	err      bool
+}{
+	{"example.com", "example.com", "443", false},
+	{"example.com:8080", "example.com", "8080", false},
+	{"example.com:", "example.com", "443", false},
+	{"2001:db8::1", "2001:db8::1", "443", false},
+	{"[2001:db8::1]:8080", "2001:db8::1", "8080", false},
+	{":8080", "", "", true},
+	{"", "", "", true},
+}
+
+func TestSplitTarget(t *testing.T) {
+	for i, tc := range splitTargetTests {
+		host, port, err := splitTarget(tc.endpoint)
+		if host != tc.host || port != tc.port || (err != nil) != tc.err {
+			t.Errorf("#%d test failed: %q should be split into %q, %q, got %q, %q, %v", i, tc.endpoint, tc.host, tc.port, host, port, err)
+		}
+	}
+}
+
+// reloader is the host table reloaded by the tests.
+type reloader interface {
+	Table
+	Reload(r io.Reader) error
+	Restart()
+}
+
+func TestBuilder(t *testing.T) {
+	h := hosts.NewHosts().(reloader)
+	if err := h.Reload(strings.NewReader("192.168.1.1 example.com\n2001:db8::1 example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBuilder("", h)
+	if scheme := b.Scheme(); scheme != Scheme {
+		t.Errorf("scheme should be %s, got %s", Scheme, scheme)
+	}
+	if _, err := b.Build(target(""), &testClientConn{}, resolver.BuildOptions{}); err == nil {
+		t.Error("build should fail for a missing target")
+	}
+
+	cc := &testClientConn{}
+	r, err := b.Build(target("example.com:8080"), cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"192.168.1.1:8080", "[2001:db8::1]:8080"}
+	if addrs := cc.addrs(); !equalStrings(addrs, expected) {
+		t.Errorf("addresses should be %v, got %v", expected, addrs)
+	}
+
+	missing := &testClientConn{}
+	if _, err := b.Build(target("example.org"), missing, resolver.BuildOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(missing.errs) != 1 || len(missing.states) != 0 {
+		t.Errorf("missing target should report an error, got %v, %v", missing.errs, missing.states)
+	}
+
+	if err := h.Reload(strings.NewReader("192.168.1.2 example.com\n192.168.1.3 example.org")); err != nil {
+		t.Fatal(err)
+	}
+	waitAddrs(t, cc, []string{"192.168.1.2:8080"})
+	waitAddrs(t, missing, []string{"192.168.1.3:443"})
+
+	r.Close()
+	if err := h.Reload(strings.NewReader("192.168.1.4 example.com")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := cc.updates(); n != 2 {
+		t.Errorf("closed resolver should not be updated, got %d updates", n)
+	}
+}
+
+// waitAddrs waits for the addresses of cc to be updated to expected.
+func waitAddrs(t *testing.T, cc *testClientConn, expected []string) {
+	t.Helper()
+
+	var addrs []string
+	for i := 0; i < 100; i++ {
+		if addrs = cc.addrs(); equalStrings(addrs, expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("addresses should be %v after reload, got %v", expected, addrs)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// subscriptions counts the subscriptions of a table.
+type subscriptions struct {
+	Table
+	n int
+}
+
+func (s *subscriptions) SubscribeAll(host string) <-chan []net.IP {
+	s.n++
+	return s.Table.SubscribeAll(host)
+}
+
+func (s *subscriptions) UnsubscribeAll(ch <-chan []net.IP) {
+	s.n--
+	s.Table.UnsubscribeAll(ch)
+}
+
+func TestResolverClose(t *testing.T) {
+	h := hosts.NewHosts(hosts.WithInitialHosts(hosts.NewHost(net.IPv4(192, 168, 1, 1), "example.com")))
+	subs := &subscriptions{Table: h.(Table)}
+	b := NewBuilder("", subs)
+
+	var resolvers []resolver.Resolver
+	for i := 0; i < 3; i++ {
+		r, err := b.Build(target("example.com"), &testClientConn{}, resolver.BuildOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolvers = append(resolvers, r)
+	}
+	if subs.n != 3 {
+		t.Errorf("subscriptions should be 3, got %d", subs.n)
+	}
+	for _, r := range resolvers {
+		r.Close()
+	}
+	if subs.n != 0 {
+		t.Errorf("subscriptions should be canceled by close, got %d", subs.n)
+	}
+}
+
+func TestBuilderUpdates(t *testing.T) {
+	h := hosts.NewHosts(hosts.WithInitialHosts(hosts.NewHost(net.IPv4(192, 168, 1, 1), "example.com"))).(reloader)
+	b := NewBuilder("", h)
+
+	cc := &testClientConn{}
+	r, err := b.Build(target("example.com"), cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	waitAddrs(t, cc, []string{"192.168.1.1:443"})
+
+	// a second address of the host, which leaves the first one as is.
+	if err := h.Reload(strings.NewReader("example.com 192.168.1.1 192.168.1.2")); err != nil {
+		t.Fatal(err)
+	}
+	waitAddrs(t, cc, []string{"192.168.1.1:443", "192.168.1.2:443"})
+
+	h.Restart()
+	if err := h.Reload(strings.NewReader("example.com 192.168.1.1 192.168.1.3")); err != nil {
+		t.Fatal(err)
+	}
+	waitAddrs(t, cc, []string{"192.168.1.1:443", "192.168.1.3:443"})
+}
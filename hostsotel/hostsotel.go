@@ -0,0 +1,51 @@
+// Package hostsotel traces the lookups of the host tables of github.com/go-gost/hosts with OpenTelemetry.
+// It is a separate module, so that the hosts package does not depend on OpenTelemetry.
+package hostsotel
+
+import (
+	"context"
+	"net"
+
+	"github.com/go-gost/hosts"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanName is the name of the spans of the lookups.
+const SpanName = "hosts.Lookup"
+
+// The attributes of the spans of the lookups.
+const (
+	QueryKey = attribute.Key("hosts.query")
+	HitKey   = attribute.Key("hosts.hit")
+	IPKey    = attribute.Key("hosts.ip")
+)
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// Tracer returns a hosts.Tracer starting the spans of the lookups with t, for the option hosts.WithTracer.
+func Tracer(t trace.Tracer) hosts.Tracer {
+	return &tracer{tracer: t}
+}
+
+// StartLookup implements hosts.Tracer.
+func (t *tracer) StartLookup(ctx context.Context, host string) hosts.LookupSpan {
+	_, span := t.tracer.Start(ctx, SpanName, trace.WithAttributes(QueryKey.String(host)))
+	return lookupSpan{span}
+}
+
+type lookupSpan struct {
+	span trace.Span
+}
+
+// End implements hosts.LookupSpan.
+func (s lookupSpan) End(ip net.IP) {
+	if ip != nil {
+		s.span.SetAttributes(HitKey.Bool(true), IPKey.String(ip.String()))
+	} else {
+		s.span.SetAttributes(HitKey.Bool(false))
+	}
+	s.span.End()
+}
@@ -0,0 +1,44 @@
+package hostsotel
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/go-gost/hosts"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	h := hosts.NewHosts(
+		hosts.WithInitialHosts(hosts.NewHost(net.IPv4(192, 168, 1, 1), "example.com")),
+		hosts.WithTracer(Tracer(provider.Tracer("hosts"))),
+	)
+
+	h.(interface {
+		LookupContext(ctx context.Context, host string) (net.IP, error)
+	}).LookupContext(context.Background(), "example.com")
+	h.Lookup("example.org")
+
+	tests := [][]attribute.KeyValue{
+		{QueryKey.String("example.com"), HitKey.Bool(true), IPKey.String("192.168.1.1")},
+		{QueryKey.String("example.org"), HitKey.Bool(false)},
+	}
+	spans := recorder.Ended()
+	if len(spans) != len(tests) {
+		t.Fatalf("spans should be %d, got %d", len(tests), len(spans))
+	}
+	for i, tc := range tests {
+		if name := spans[i].Name(); name != SpanName {
+			t.Errorf("#%d test failed: name should be %s, got %s", i, SpanName, name)
+		}
+		attrs := attribute.NewSet(spans[i].Attributes()...)
+		if expected := attribute.NewSet(tc...); !attrs.Equals(&expected) {
+			t.Errorf("#%d test failed: attributes should be %v, got %v", i, expected.ToSlice(), attrs.ToSlice())
+		}
+	}
+}
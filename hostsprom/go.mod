@@ -0,0 +1,8 @@
+module github.com/go-gost/hosts/hostsprom
+
+go 1.13
+
+require (
+	github.com/go-gost/hosts v0.0.0-20261014084954-101cbcc9d38f
+	github.com/prometheus/client_golang v1.11.1
+)
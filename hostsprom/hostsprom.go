@@ -0,0 +1,79 @@
+// Package hostsprom exports the statistics of the host tables of github.com/go-gost/hosts as Prometheus metrics.
+// It is a separate module, so that the hosts package does not depend on the Prometheus client.
+package hostsprom
+
+import (
+	"github.com/go-gost/hosts"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Table is the host table exported by Collector, the Hosts returned by hosts.NewHosts implements it.
+type Table interface {
+	Count() int
+	Stats() hosts.Stats
+}
+
+type collector struct {
+	table Table
+
+	entries      *prometheus.Desc
+	lookups      *prometheus.Desc
+	hits         *prometheus.Desc
+	misses       *prometheus.Desc
+	reloads      *prometheus.Desc
+	reloadErrors *prometheus.Desc
+	lookupTime   *prometheus.Desc
+}
+
+// Collector returns a prometheus.Collector of the entry count and the statistics of t,
+// labeled with table="name" so that the collectors of several tables can be registered together.
+func Collector(name string, t Table) prometheus.Collector {
+	labels := prometheus.Labels{"table": name}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, nil, labels)
+	}
+	return &collector{
+		table:        t,
+		entries:      desc("hosts_entries", "Number of entries in the host table."),
+		lookups:      desc("hosts_lookups_total", "Total number of lookups."),
+		hits:         desc("hosts_lookup_hits_total", "Number of lookups with the host found."),
+		misses:       desc("hosts_lookup_misses_total", "Number of lookups with the host not found."),
+		reloads:      desc("hosts_reloads_total", "Number of successful reloads."),
+		reloadErrors: desc("hosts_reload_errors_total", "Number of failed reloads."),
+		lookupTime:   desc("hosts_lookup_duration_seconds", "Durations of the lookups, timed with hosts.WithLookupTiming."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries
+	ch <- c.lookups
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.reloads
+	ch <- c.reloadErrors
+	ch <- c.lookupTime
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.table.Stats()
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(c.table.Count()))
+	ch <- prometheus.MustNewConstMetric(c.lookups, prometheus.CounterValue, float64(stats.Lookups))
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.reloads, prometheus.CounterValue, float64(stats.Reloads))
+	ch <- prometheus.MustNewConstMetric(c.reloadErrors, prometheus.CounterValue, float64(stats.ReloadErrors))
+	ch <- lookupTimeHistogram(c.lookupTime, stats.LookupTime)
+}
+
+// lookupTimeHistogram converts t to a histogram, whose buckets are cumulative.
+func lookupTimeHistogram(desc *prometheus.Desc, t hosts.LookupTime) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(hosts.LookupTimeBuckets))
+	var n uint64
+	for i, bound := range hosts.LookupTimeBuckets {
+		n += t.Buckets[i]
+		buckets[bound.Seconds()] = n
+	}
+	return prometheus.MustNewConstHistogram(desc, t.Count, t.Total.Seconds(), buckets)
+}
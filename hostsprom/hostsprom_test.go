@@ -0,0 +1,49 @@
+package hostsprom
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-gost/hosts"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	h := hosts.NewHosts(hosts.WithLookupTiming(true))
+	if err := h.(interface{ Reload(r io.Reader) error }).Reload(strings.NewReader("192.168.1.1 example.com\n192.168.1.2 example.org")); err != nil {
+		t.Fatal(err)
+	}
+	h.Lookup("example.com")
+	h.Lookup("example.net")
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(Collector("a", h.(Table)), Collector("b", hosts.NewHosts().(Table)))
+
+	expected := `
+# HELP hosts_entries Number of entries in the host table.
+# TYPE hosts_entries gauge
+hosts_entries{table="a"} 2
+hosts_entries{table="b"} 0
+# HELP hosts_lookup_hits_total Number of lookups with the host found.
+# TYPE hosts_lookup_hits_total counter
+hosts_lookup_hits_total{table="a"} 1
+hosts_lookup_hits_total{table="b"} 0
+# HELP hosts_lookup_misses_total Number of lookups with the host not found.
+# TYPE hosts_lookup_misses_total counter
+hosts_lookup_misses_total{table="a"} 1
+hosts_lookup_misses_total{table="b"} 0
+# HELP hosts_reloads_total Number of successful reloads.
+# TYPE hosts_reloads_total counter
+hosts_reloads_total{table="a"} 1
+hosts_reloads_total{table="b"} 0
+`
+	if n := testutil.CollectAndCount(Collector("a", h.(Table)), "hosts_lookup_duration_seconds"); n != 1 {
+		t.Errorf("lookup duration should be collected, got %d metrics", n)
+	}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"hosts_entries", "hosts_lookup_hits_total", "hosts_lookup_misses_total", "hosts_reloads_total"); err != nil {
+		t.Error(err)
+	}
+}
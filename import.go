@@ -0,0 +1,40 @@
+package hosts
+
+import (
+	"errors"
+)
+
+// ErrUnsupported is returned by Import for a Hosts which does not support the enumeration or the addition of entries.
+var ErrUnsupported = errors.New("unsupported by the Hosts")
+
+// Lister is a Hosts whose entries can be enumerated, such as the one returned by NewHosts.
+type Lister interface {
+	GetAll() []Host
+}
+
+// Adder is a Hosts to which entries can be added, such as the one returned by NewHosts.
+type Adder interface {
+	Add(host Host) error
+}
+
+// Import adds the entries of src, enumerated by its GetAll, to dst by its Add,
+// so that the names already in dst are resolved by the conflict strategy of dst.
+// It stops at the first entry dst fails to add, and returns the error, the earlier entries are left in dst.
+// ErrUnsupported is returned if src is not a Lister or dst is not an Adder.
+func Import(dst Hosts, src Hosts) error {
+	adder, ok := dst.(Adder)
+	if !ok {
+		return ErrUnsupported
+	}
+	lister, ok := src.(Lister)
+	if !ok {
+		return ErrUnsupported
+	}
+
+	for _, host := range lister.GetAll() {
+		if err := adder.Add(host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
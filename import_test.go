@@ -0,0 +1,54 @@
+package hosts
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	src := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 2), "example.com", "www.example.com"),
+		NewHost(net.IPv4(192, 168, 1, 3), "example.org"),
+	))
+
+	tests := []struct {
+		conflict ConflictStrategy
+		ips      []net.IP
+		count    int
+		err      bool
+	}{
+		{ConflictFirstWins, []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}, 3, false},
+		{ConflictLastWins, []net.IP{net.IPv4(192, 168, 1, 2)}, 2, false},
+		{ConflictError, []net.IP{net.IPv4(192, 168, 1, 1)}, 1, true},
+	}
+	for i, tc := range tests {
+		dst := NewHosts(
+			WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com")),
+			WithConflictStrategy(tc.conflict),
+		)
+		err := Import(dst, src)
+		var dup *DuplicateError
+		if tc.err != errors.As(err, &dup) {
+			t.Errorf("#%d test failed: import error should be a duplicate %v, got %v", i, tc.err, err)
+		}
+		if ips := dst.LookupAll("example.com"); !equalIPs(ips, tc.ips) {
+			t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+		}
+		if n := dst.(*staticHosts).Count(); n != tc.count {
+			t.Errorf("#%d test failed: count should be %d, got %d", i, tc.count, n)
+		}
+	}
+
+	dst := NewHosts()
+	if err := Import(dst, NewMultiHosts(src, NewMultiHosts())); err != nil {
+		t.Fatal(err)
+	}
+	if n := dst.(*staticHosts).Count(); n != 2 {
+		t.Errorf("count should be 2 after importing a MultiHosts, got %d", n)
+	}
+
+	if err := Import(NewMultiHosts(), src); err != ErrUnsupported {
+		t.Errorf("import to a MultiHosts should be unsupported, got %v", err)
+	}
+}
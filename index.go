@@ -0,0 +1,392 @@
+package hosts
+
+import (
+	"net"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// table is a version of the host table, it is never modified once created,
+// so that it can be read without a lock while a new version replaces it.
+type table struct {
+	hosts   []Host
+	index   *index
+	nets    []netHost
+	cnames  map[string]cname
+	negated map[string]string // the negated names by their keys
+}
+
+func newTable(hosts []Host, nets []netHost, key func(string) string) *table {
+	return &table{
+		hosts: hosts,
+		index: buildIndex(hosts, key),
+		nets:  nets,
+	}
+}
+
+// withHosts returns a copy of t with the entries replaced by hosts, and the index rebuilt.
+func (t *table) withHosts(hosts []Host, key func(string) string) *table {
+	v := newTable(hosts, t.nets, key)
+	v.cnames = t.cnames
+	v.negated = t.negated
+	return v
+}
+
+// index maps the names of the hosts to their positions in the host table.
+type index struct {
+	// names maps each hostname and alias to the indexes of the hosts it belongs to.
+	names map[string][]int
+	// wildcards is the trie of the domains of the wildcard names (*.example.com -> example.com).
+	wildcards *labelNode
+	// patterns holds the regular expressions of the pattern names (re:expr) in the order they appear.
+	patterns []pattern
+	// catchAll holds the indexes of the hosts with the catch-all name (*).
+	catchAll []int
+	// v4 and v6 are the numbers of the hosts with an IPv4 and IPv6 address.
+	v4, v6 int
+	// addrs maps each IP address to the indexes of the hosts it belongs to.
+	addrs map[string][]int
+	// counters holds the round-robin counter (*uint32) of each looked up name.
+	counters sync.Map
+	// key converts the names to their keys.
+	key func(string) string
+	// fold is whether key folds the case of the names, so that the patterns are matched case-insensitively.
+	fold bool
+}
+
+// newIndex creates an empty index.
+func newIndex(key func(string) string) *index {
+	return &index{
+		key:       key,
+		fold:      key("A") == "a",
+		names:     make(map[string][]int),
+		wildcards: &labelNode{},
+		addrs:     make(map[string][]int),
+	}
+}
+
+// buildIndex creates the index for hosts, the indexes are kept in the order the hosts appear.
+// Hosts without an IP are not indexed.
+func buildIndex(hosts []Host, key func(string) string) *index {
+	idx := newIndex(key)
+	for i, host := range hosts {
+		idx.addHost(host, i)
+	}
+	return idx
+}
+
+// addHost indexes host at i, the hosts must be added in the order of their indexes.
+func (idx *index) addHost(host Host, i int) {
+	if host.IP == nil {
+		return
+	}
+	addr := ipKey(host.IP)
+	idx.addrs[addr] = append(idx.addrs[addr], i)
+	if host.IP.To4() != nil {
+		idx.v4++
+	} else {
+		idx.v6++
+	}
+
+	hostname := host.Hostname
+	if !isPattern(hostname) {
+		hostname = idx.key(hostname)
+	}
+	idx.add(hostname, i)
+	for _, alias := range host.Aliases {
+		if !isPattern(alias) {
+			alias = idx.key(alias)
+		}
+		if alias != hostname {
+			idx.add(alias, i)
+		}
+	}
+}
+
+// add indexes the name key or the pattern name for the host at i.
+func (idx *index) add(name string, i int) {
+	if name == "" {
+		return
+	}
+	if isPattern(name) {
+		idx.addPattern(name, i)
+		return
+	}
+	if name == catchAllName {
+		idx.catchAll = append(idx.catchAll, i)
+		return
+	}
+	if strings.HasPrefix(name, "*.") {
+		if domain := name[2:]; domain != "" {
+			idx.wildcards.insert(domain, i)
+		}
+		return
+	}
+	idx.names[name] = append(idx.names[name], i)
+}
+
+// addPattern indexes the pattern name for the host at i,
+// the hosts with the same pattern share the compiled pattern.
+// Invalid patterns are not indexed.
+func (idx *index) addPattern(name string, i int) {
+	for j := range idx.patterns {
+		if idx.patterns[j].name == name {
+			idx.patterns[j].entries = append(idx.patterns[j].entries, i)
+			return
+		}
+	}
+	match, err := compilePattern(name, idx.fold)
+	if err != nil {
+		return
+	}
+	idx.patterns = append(idx.patterns, pattern{name: name, match: match, entries: []int{i}})
+}
+
+// lookupExact returns the indexes of the hosts with the exact name host, which may have a port.
+func (idx *index) lookupExact(host string) []int {
+	if idx == nil {
+		return nil
+	}
+	return idx.names[idx.key(trimPort(host))]
+}
+
+// lookup returns the indexes of the hosts matching host, which may have a port.
+// Exact names take precedence over wildcards, a more specific wildcard
+// takes precedence over a less specific one, and wildcards take precedence over patterns.
+func (idx *index) lookup(host string) []int {
+	if idx == nil {
+		return nil
+	}
+	host = idx.key(trimPort(host))
+	if v, ok := idx.names[host]; ok {
+		return v
+	}
+	if strings.HasPrefix(host, "*.") {
+		return nil
+	}
+	if v := idx.lookupWildcard(host); v != nil {
+		return v
+	}
+	return idx.lookupPattern(host)
+}
+
+// lookupWildcard returns the indexes of the hosts of the most specific wildcard matching the key host.
+func (idx *index) lookupWildcard(host string) []int {
+	return idx.wildcards.match(host)
+}
+
+// lookupPattern returns the indexes of the hosts of the first pattern matching the key host.
+func (idx *index) lookupPattern(host string) []int {
+	for i := range idx.patterns {
+		if idx.patterns[i].match(host) {
+			return idx.patterns[i].entries
+		}
+	}
+	return nil
+}
+
+// labelNode is a node of a trie of domains keyed by their labels from right to left,
+// so that the wildcards matching a name are found in O(labels) time.
+type labelNode struct {
+	children map[string]*labelNode
+	entries  []int // indexes of the hosts of the wildcard of the domain ending at the node, if any
+}
+
+// insert adds the index i of a host with the wildcard of domain.
+func (n *labelNode) insert(domain string, i int) {
+	for domain != "" {
+		var label string
+		if k := strings.LastIndexByte(domain, '.'); k >= 0 {
+			label, domain = domain[k+1:], domain[:k]
+		} else {
+			label, domain = domain, ""
+		}
+		child := n.children[label]
+		if child == nil {
+			if n.children == nil {
+				n.children = make(map[string]*labelNode)
+			}
+			child = &labelNode{}
+			n.children[label] = child
+		}
+		n = child
+	}
+	n.entries = append(n.entries, i)
+}
+
+// match returns the indexes of the hosts of the longest domain which is a proper suffix of name.
+func (n *labelNode) match(name string) (entries []int) {
+	for n != nil && len(n.children) > 0 {
+		k := strings.LastIndexByte(name, '.')
+		if k < 0 {
+			break // the name itself is not matched by its wildcard
+		}
+		n = n.children[name[k+1:]]
+		name = name[:k]
+		if n != nil && n.entries != nil {
+			entries = n.entries
+		}
+	}
+	return
+}
+
+// catchAllName is the name of the catch-all entry, which matches the names not matched by any other entry.
+const catchAllName = "*"
+
+const (
+	// patternPrefix is the prefix of the regular expression pattern names,
+	// re:expr matches the names matched by the regular expression expr.
+	patternPrefix = "re:"
+	// globPrefix is the prefix of the glob pattern names, glob:pattern matches the names matched by
+	// the shell pattern like path.Match, with the labels of the names in place of the path elements,
+	// so that * and ? never match a dot.
+	globPrefix = "glob:"
+)
+
+// pattern is a compiled pattern name.
+type pattern struct {
+	name    string
+	match   func(host string) bool
+	entries []int // indexes of the hosts the pattern belongs to
+}
+
+// isPattern checks whether name is a pattern name, re:expr or glob:pattern.
+func isPattern(name string) bool {
+	return strings.HasPrefix(name, patternPrefix) || strings.HasPrefix(name, globPrefix)
+}
+
+// compilePattern compiles the pattern name, which must match the whole name.
+// With fold the pattern matches the names regardless of case, the names are expected to be lower-cased.
+func compilePattern(name string, fold bool) (func(host string) bool, error) {
+	if strings.HasPrefix(name, globPrefix) {
+		glob := labelsToPath(name[len(globPrefix):])
+		if fold {
+			glob = strings.ToLower(glob)
+		}
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, err
+		}
+		return func(host string) bool {
+			ok, _ := path.Match(glob, labelsToPath(host))
+			return ok
+		}, nil
+	}
+
+	expr := "^(?:" + strings.TrimPrefix(name, patternPrefix) + ")$"
+	if fold {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// labelsToPath converts the labels of the name s to the elements of a path, for the matching of the glob patterns.
+func labelsToPath(s string) string {
+	return strings.Replace(s, ".", "/", -1)
+}
+
+// next returns the current round-robin counter of name, then increments it.
+func (idx *index) next(name string) uint32 {
+	v, ok := idx.counters.Load(name)
+	if !ok {
+		v, _ = idx.counters.LoadOrStore(name, new(uint32))
+	}
+	return atomic.AddUint32(v.(*uint32), 1) - 1
+}
+
+// lookupAddr returns the indexes of the hosts with the IP address ip.
+func (idx *index) lookupAddr(ip net.IP) []int {
+	if idx == nil || ip == nil {
+		return nil
+	}
+	return idx.addrs[ipKey(ip)]
+}
+
+// trimPort returns the host part of a host:port address, the brackets of an IPv6 literal are removed.
+// Other names are returned as is.
+func trimPort(host string) string {
+	if strings.IndexByte(host, ':') < 0 {
+		return host
+	}
+	if v, _, err := net.SplitHostPort(host); err == nil {
+		return v
+	}
+	if len(host) > 1 && host[0] == '[' && host[len(host)-1] == ']' {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+// isIPLiteral checks whether host, which may have a port, is an IP address rather than a name.
+func isIPLiteral(host string) bool {
+	ip, _ := parseIPZone(trimPort(host))
+	return ip != nil
+}
+
+// ipKey returns the key of ip in the index,
+// an IPv4 address and its IPv4-in-IPv6 form have the same key.
+func ipKey(ip net.IP) string {
+	return string(ip.To16())
+}
+
+// idnaProfile converts internationalized domain names to their ASCII form for lookup.
+// Non-strict domain names are allowed, so that wildcards and underscores are accepted.
+var idnaProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false))
+
+// normalize returns the form of the name used for matching,
+// the names stored in the hosts are left untouched.
+// Names that are not valid internationalized domain names are only lower cased.
+// A single trailing dot of a fully qualified name is removed, the root name "." is kept as is.
+func normalize(name string) string {
+	s, err := toASCII(name)
+	if err != nil {
+		s = strings.ToLower(name)
+	}
+	return trimDot(s)
+}
+
+// normalizeCase is like normalize, but keeps the case of ASCII names.
+func normalizeCase(name string) string {
+	s := name
+	if !isASCII(name) {
+		if v, err := idnaProfile.ToASCII(name); err == nil {
+			s = v
+		}
+	}
+	return trimDot(s)
+}
+
+// trimDot removes a single trailing dot of name, unless name is the root name ".".
+func trimDot(name string) string {
+	if n := len(name) - 1; n > 0 && name[n] == '.' {
+		return name[:n]
+	}
+	return name
+}
+
+// toASCII converts name to its lower case ASCII (punycode) form,
+// an error is returned if name is not a valid internationalized domain name.
+func toASCII(name string) (string, error) {
+	if !isASCII(name) {
+		return idnaProfile.ToASCII(name)
+	}
+	return strings.ToLower(name), nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
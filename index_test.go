@@ -0,0 +1,55 @@
+package hosts
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+var labelNodeMatchTests = []struct {
+	domains []string
+	name    string
+	entries []int
+}{
+	{nil, "example.com", nil},
+	{[]string{"example.com"}, "example.com", nil},
+	{[]string{"example.com"}, "www.example.com", []int{0}},
+	{[]string{"example.com"}, "a.b.example.com", []int{0}},
+	{[]string{"example.com"}, "www.example.org", nil},
+	{[]string{"example.com"}, "wwwexample.com", nil},
+	{[]string{"example.com", "b.example.com"}, "a.b.example.com", []int{1}},
+	{[]string{"b.example.com", "example.com"}, "a.b.example.com", []int{0}},
+	{[]string{"b.example.com", "example.com"}, "b.example.com", []int{1}},
+	{[]string{"b.example.com", "example.com"}, "a.c.example.com", []int{1}},
+	{[]string{"example.com", "example.com"}, "www.example.com", []int{0, 1}},
+	{[]string{"com"}, "www.example.com", []int{0}},
+}
+
+func TestLabelNodeMatch(t *testing.T) {
+	for i, tc := range labelNodeMatchTests {
+		root := &labelNode{}
+		for j, domain := range tc.domains {
+			root.insert(domain, j)
+		}
+		if entries := root.match(tc.name); !reflect.DeepEqual(entries, tc.entries) {
+			t.Errorf("#%d test failed: match should be %v, got %v", i, tc.entries, entries)
+		}
+	}
+}
+
+func TestIndexLookupManyWildcards(t *testing.T) {
+	var hosts []Host
+	for i := 0; i < 5000; i++ {
+		hosts = append(hosts, NewHost(net.IPv4(10, 0, byte(i>>8), byte(i)), fmt.Sprintf("*.d%d.example.com", i)))
+	}
+	hosts = append(hosts, NewHost(net.IPv4(192, 168, 1, 1), "*.example.com"))
+	idx := buildIndex(hosts, normalize)
+
+	if v := idx.lookup("www.d4999.example.com"); !reflect.DeepEqual(v, []int{4999}) {
+		t.Errorf("lookup should be %v, got %v", []int{4999}, v)
+	}
+	if v := idx.lookup("d4999.example.com"); !reflect.DeepEqual(v, []int{5000}) {
+		t.Errorf("lookup should be %v, got %v", []int{5000}, v)
+	}
+}
@@ -0,0 +1,15 @@
+package hosts
+
+// Logger is the logger used by Hosts to report the diagnostics of reloading, *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
+// logf logs with the logger.
+func (h *staticHosts) logf(format string, v ...interface{}) {
+	h.options.logger.Printf(format, v...)
+}
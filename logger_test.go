@@ -0,0 +1,58 @@
+package hosts
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read error")
+}
+
+func TestHostsLogger(t *testing.T) {
+	var b bytes.Buffer
+	hosts := NewHosts(WithLogger(log.New(&b, "", 0))).(*staticHosts)
+
+	if err := hosts.Reload(strings.NewReader("192.168.1.1 example.com\nfoo example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := hosts.Reload(errReader{}); err == nil {
+		t.Error("reload should fail")
+	}
+	hosts.reloadSource(func() (io.Reader, error) {
+		return nil, errors.New("source error")
+	})
+
+	expected := "hosts: skip line 2: invalid IP address: \"foo example.com\"\n" +
+		"hosts: reloaded 1 entries\n" +
+		"hosts: reload: read error\n" +
+		"hosts: reload: source error\n"
+	if s := b.String(); s != expected {
+		t.Errorf("log should be %q, got %q", expected, s)
+	}
+
+	// a successful reload is logged even if it leaves the table unchanged.
+	b.Reset()
+	for _, config := range []string{"192.168.1.1 example.com\nfoo example.com", "192.168.1.1  example.com\nfoo example.com"} {
+		if err := hosts.Reload(strings.NewReader(config)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected = strings.Repeat("hosts: skip line 2: invalid IP address: \"foo example.com\"\nhosts: reloaded 1 entries\n", 2)
+	if s := b.String(); s != expected {
+		t.Errorf("log should be %q, got %q", expected, s)
+	}
+
+	b.Reset()
+	hosts = NewHosts(WithLogger(nil)).(*staticHosts)
+	hosts.Reload(strings.NewReader("foo example.com"))
+	if b.Len() > 0 {
+		t.Errorf("log should be empty, got %q", b.String())
+	}
+}
@@ -0,0 +1,90 @@
+package hosts
+
+import (
+	"net"
+	"sort"
+	"sync"
+)
+
+// MapHosts is a Hosts backed by a map from hostname to IP address, such as a test double of Hosts.
+// The hostnames are matched exactly, and each hostname has a single address.
+// The zero value is an empty MapHosts ready to use, it is safe for concurrent use.
+type MapHosts struct {
+	m   map[string]net.IP
+	mux sync.RWMutex
+}
+
+// NewMapHosts creates a MapHosts with a copy of the entries of m.
+func NewMapHosts(m map[string]net.IP) *MapHosts {
+	h := &MapHosts{
+		m: make(map[string]net.IP, len(m)),
+	}
+	for host, ip := range m {
+		h.m[host] = copyIP(ip)
+	}
+	return h
+}
+
+// Set maps host to ip, replacing the address host is mapped to, if any.
+func (h *MapHosts) Set(host string, ip net.IP) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if h.m == nil {
+		h.m = make(map[string]net.IP)
+	}
+	h.m[host] = copyIP(ip)
+}
+
+// Delete removes the mapping of host.
+func (h *MapHosts) Delete(host string) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	delete(h.m, host)
+}
+
+// Lookup returns a copy of the address host is mapped to, or nil if host is not mapped.
+func (h *MapHosts) Lookup(host string) net.IP {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	return copyIP(h.m[host])
+}
+
+// LookupAll returns the address host is mapped to as a single address, or nil if host is not mapped.
+func (h *MapHosts) LookupAll(host string) []net.IP {
+	if ip := h.Lookup(host); ip != nil {
+		return []net.IP{ip}
+	}
+	return nil
+}
+
+// ReverseLookup returns the hostnames mapped to ip, in sorted order.
+func (h *MapHosts) ReverseLookup(ip net.IP) []string {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	var names []string
+	for host, v := range h.m {
+		if v.Equal(ip) {
+			names = append(names, host)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Exists checks whether host is mapped.
+func (h *MapHosts) Exists(host string) bool {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	_, ok := h.m[host]
+	return ok
+}
+
+// HasIP checks whether a hostname is mapped to ip.
+func (h *MapHosts) HasIP(ip net.IP) bool {
+	return len(h.ReverseLookup(ip)) > 0
+}
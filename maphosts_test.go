@@ -0,0 +1,61 @@
+package hosts
+
+import (
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestMapHosts(t *testing.T) {
+	var hosts Hosts = NewMapHosts(map[string]net.IP{
+		"example.com": net.IPv4(192, 168, 1, 1),
+		"example.org": net.IPv4(192, 168, 1, 1),
+	})
+	m := hosts.(*MapHosts)
+
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+	if names := hosts.ReverseLookup(net.IPv4(192, 168, 1, 1)); len(names) != 2 || names[0] != "example.com" || names[1] != "example.org" {
+		t.Errorf("reverse lookup should be [example.com example.org], got %v", names)
+	}
+
+	m.Set("example.com", net.IPv4(192, 168, 1, 2))
+	m.Delete("example.org")
+	if ips := hosts.LookupAll("example.com"); !equalIPs(ips, []net.IP{net.IPv4(192, 168, 1, 2)}) {
+		t.Errorf("lookup should be [%s], got %v", net.IPv4(192, 168, 1, 2), ips)
+	}
+	if hosts.Exists("example.org") || hosts.LookupAll("example.org") != nil {
+		t.Error("example.org should be deleted")
+	}
+	if !hosts.HasIP(net.IPv4(192, 168, 1, 2)) || hosts.HasIP(net.IPv4(192, 168, 1, 1)) {
+		t.Error("only 192.168.1.2 should be mapped")
+	}
+
+	hosts.Lookup("example.com")[0] = 0
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 2)) {
+		t.Errorf("lookup should return a copy, got %s", ip)
+	}
+}
+
+func TestMapHostsConcurrent(t *testing.T) {
+	var m MapHosts
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Set("example.com", net.IPv4(192, 168, 1, byte(i)))
+				m.Lookup("example.com")
+				m.ReverseLookup(net.IPv4(192, 168, 1, byte(i)))
+				m.Delete("example.org")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if !m.Exists("example.com") {
+		t.Error("example.com should be mapped")
+	}
+}
@@ -0,0 +1,185 @@
+package hosts
+
+import (
+	"path"
+	"strings"
+)
+
+// Matcher decides whether an entry of the host table matches a looked up host.
+// The host is passed without its port, matchers are responsible for the normalization of the names.
+type Matcher interface {
+	Match(entry Host, query string) bool
+}
+
+// MatcherFunc is an adapter to use an ordinary function as a Matcher.
+type MatcherFunc func(entry Host, query string) bool
+
+// Match calls f(entry, query).
+func (f MatcherFunc) Match(entry Host, query string) bool {
+	return f(entry, query)
+}
+
+// ExactMatcher returns a Matcher which matches the entries with the query as their hostname or an alias,
+// case-insensitively. Unlike the default lookup, wildcards and patterns are not interpreted.
+func ExactMatcher() Matcher {
+	return MatcherFunc(func(entry Host, query string) bool {
+		query = normalize(query)
+		return matchNames(entry, func(name string) bool {
+			return normalize(name) == query
+		})
+	})
+}
+
+// SuffixMatcher returns a Matcher which matches the entries with the query or a parent domain of the query
+// as their hostname or an alias, case-insensitively, e.g. an entry of example.com matches www.example.com.
+func SuffixMatcher() Matcher {
+	return MatcherFunc(func(entry Host, query string) bool {
+		query = normalize(query)
+		return matchNames(entry, func(name string) bool {
+			name = normalize(name)
+			return query == name || strings.HasSuffix(query, "."+name)
+		})
+	})
+}
+
+// GlobMatcher returns a Matcher which matches the entries with a hostname or an alias matching the query
+// as a shell pattern by path.Match, case-insensitively, e.g. an entry of ads*.example.com matches ads1.example.com.
+// A "*" matches any sequence of characters including dots.
+func GlobMatcher() Matcher {
+	return MatcherFunc(func(entry Host, query string) bool {
+		query = normalize(query)
+		return matchNames(entry, func(name string) bool {
+			ok, err := path.Match(normalize(name), query)
+			return ok && err == nil
+		})
+	})
+}
+
+// matchNames checks whether the hostname or any alias of entry satisfies match.
+func matchNames(entry Host, match func(name string) bool) bool {
+	if entry.Hostname != "" && match(entry.Hostname) {
+		return true
+	}
+	for _, alias := range entry.Aliases {
+		if alias != "" && match(alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupIndexes returns the indexes of the hosts of t matching host.
+// A negated name only matches the entries with the exact name.
+func (h *staticHosts) lookupIndexes(t *table, host string) []int {
+	if h.cache != nil {
+		if v, ok := h.cache.get(t, host); ok {
+			return v
+		}
+	}
+	var v []int
+	if len(t.negated) > 0 && t.negated[h.key(trimPort(host))] != "" {
+		v = t.index.lookupExact(host)
+	} else {
+		v = h.lookupMatch(t, host)
+	}
+	if h.cache != nil {
+		h.cache.add(t, host, v)
+	}
+	return v
+}
+
+// lookupMatch returns the indexes of the hosts of t matching host,
+// or the ones of the hostname host is an alias of by the cname option, or the ones of the catch-all entry.
+// An IP literal is only matched by the entries with the exact name, never by a wildcard, pattern or the catch-all entry.
+func (h *staticHosts) lookupMatch(t *table, host string) []int {
+	if isIPLiteral(host) {
+		return t.index.lookupExact(host)
+	}
+	v := h.matchIndexes(t, host)
+	if len(v) == 0 && len(t.cnames) > 0 {
+		v = h.lookupCNAME(t, host)
+	}
+	if len(v) == 0 {
+		v = t.index.catchAll
+	}
+	return v
+}
+
+// matchIndexes returns the indexes of the hosts of t matching host.
+// The entries are matched by the matcher of the options if any, otherwise they are looked up in the index.
+func (h *staticHosts) matchIndexes(t *table, host string) []int {
+	m := h.options.matcher
+	if m == nil {
+		return t.index.lookup(host)
+	}
+
+	host = trimPort(host)
+	key := h.key(host)
+	longest := h.options.matchPolicy == LongestMatch
+	var v, exact []int
+	best := 0
+	for i := range t.hosts {
+		entry := t.hosts[i]
+		if entry.IP == nil || !m.Match(entry, host) {
+			continue
+		}
+		if hasName(entry, key, h.key) {
+			exact = append(exact, i)
+			continue
+		}
+		if longest {
+			n := matchLength(m, entry, host)
+			if n < best {
+				continue
+			}
+			if n > best {
+				best, v = n, v[:0]
+			}
+		}
+		v = append(v, i)
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return v
+}
+
+// MatchPolicy is the way to select the entries matching a host by the matcher set by WithMatcher.
+// The entries with the host itself as their hostname or an alias always take precedence regardless of the policy.
+type MatchPolicy int
+
+const (
+	// FirstMatch selects all the matching entries in the order they appear, so Lookup returns the first one.
+	// It is the default.
+	FirstMatch MatchPolicy = iota
+	// LongestMatch selects the entries with the longest matching name, which is the most specific one
+	// for the wildcard and suffix matchers, e.g. an entry of api.example.com rather than example.com
+	// for v1.api.example.com with SuffixMatcher.
+	LongestMatch
+)
+
+// hasName checks whether the key k is the key of the hostname or an alias of entry.
+func hasName(entry Host, k string, key func(string) string) bool {
+	if entry.Hostname != "" && key(entry.Hostname) == k {
+		return true
+	}
+	for _, alias := range entry.Aliases {
+		if alias != "" && key(alias) == k {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLength returns the length of the longest name of entry which matches host by m on its own,
+// or zero if no single name matches.
+func matchLength(m Matcher, entry Host, host string) int {
+	n := 0
+	matchNames(entry, func(name string) bool {
+		if len(name) > n && m.Match(Host{IP: entry.IP, Zone: entry.Zone, Hostname: name}, host) {
+			n = len(name)
+		}
+		return false
+	})
+	return n
+}
@@ -0,0 +1,103 @@
+package hosts
+
+import (
+	"net"
+	"testing"
+)
+
+var matcherTests = []struct {
+	matcher Matcher
+	host    Host
+	query   string
+	match   bool
+}{
+	{ExactMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "example.com"), "example.com", true},
+	{ExactMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"), "EXAMPLE.", true},
+	{ExactMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "example.com"), "www.example.com", false},
+	{ExactMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "*.example.com"), "www.example.com", false},
+	{SuffixMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "example.com"), "example.com", true},
+	{SuffixMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "example.com"), "a.b.Example.com", true},
+	{SuffixMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "example.org", "example.com"), "www.example.com", true},
+	{SuffixMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "example.com"), "wwwexample.com", false},
+	{GlobMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "ads*.example.com"), "ads1.example.com", true},
+	{GlobMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "*.example.com"), "a.b.example.com", true},
+	{GlobMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "ads?.example.com"), "ADS1.example.com", true},
+	{GlobMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "ads?.example.com"), "ads12.example.com", false},
+	{GlobMatcher(), NewHost(net.IPv4(192, 168, 1, 1), "ads[.example.com"), "ads[.example.com", false},
+}
+
+func TestMatchers(t *testing.T) {
+	for i, tc := range matcherTests {
+		if match := tc.matcher.Match(tc.host, tc.query); match != tc.match {
+			t.Errorf("#%d test failed: match should be %t, got %t", i, tc.match, match)
+		}
+	}
+}
+
+func TestHostsWithMatcher(t *testing.T) {
+	hosts := NewHosts(
+		WithInitialHosts(
+			NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+			NewHost(net.IPv4(192, 168, 1, 2), "api.example.com"),
+			NewHost(net.IPv4(192, 168, 1, 3), "example.org"),
+		),
+		WithMatcher(SuffixMatcher()),
+	).(*staticHosts)
+
+	ips := hosts.LookupAll("v1.api.example.com:443")
+	if expected := []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}; !equalIPs(ips, expected) {
+		t.Errorf("lookup should be %v, got %v", expected, ips)
+	}
+	if name := hosts.Canonical("www.example.org"); name != "example.org" {
+		t.Errorf("canonical name should be %s, got %s", "example.org", name)
+	}
+	if ip := hosts.Lookup("example.net"); ip != nil {
+		t.Errorf("lookup should be nil, got %s", ip)
+	}
+
+	custom := MatcherFunc(func(entry Host, query string) bool {
+		return len(entry.Hostname) == len(query)
+	})
+	hosts = NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com")), WithMatcher(custom)).(*staticHosts)
+	if ip := hosts.Lookup("abcdefghijk"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+}
+
+var matchPolicyTests = []struct {
+	matcher Matcher
+	policy  MatchPolicy
+	host    string
+	ips     []net.IP
+}{
+	{SuffixMatcher(), FirstMatch, "v1.api.example.com", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2), net.IPv4(192, 168, 1, 3)}},
+	{SuffixMatcher(), LongestMatch, "v1.api.example.com", []net.IP{net.IPv4(192, 168, 1, 2), net.IPv4(192, 168, 1, 3)}},
+	{SuffixMatcher(), LongestMatch, "www.example.com", []net.IP{net.IPv4(192, 168, 1, 1)}},
+	{SuffixMatcher(), FirstMatch, "api.example.com", []net.IP{net.IPv4(192, 168, 1, 2), net.IPv4(192, 168, 1, 3)}},
+	{SuffixMatcher(), LongestMatch, "api.example.com", []net.IP{net.IPv4(192, 168, 1, 2), net.IPv4(192, 168, 1, 3)}},
+	{GlobMatcher(), FirstMatch, "v1.cdn.example.com", []net.IP{net.IPv4(192, 168, 1, 4), net.IPv4(192, 168, 1, 5)}},
+	{GlobMatcher(), LongestMatch, "v1.cdn.example.com", []net.IP{net.IPv4(192, 168, 1, 5)}},
+	{GlobMatcher(), LongestMatch, "cdn.example.com", []net.IP{net.IPv4(192, 168, 1, 6)}},
+	{GlobMatcher(), LongestMatch, "example.org", nil},
+}
+
+func TestHostsMatchPolicy(t *testing.T) {
+	for i, tc := range matchPolicyTests {
+		hosts := NewHosts(
+			WithInitialHosts(
+				NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+				NewHost(net.IPv4(192, 168, 1, 2), "api.example.com"),
+				NewHost(net.IPv4(192, 168, 1, 3), "api", "api.example.com"),
+				NewHost(net.IPv4(192, 168, 1, 4), "*.example.com"),
+				NewHost(net.IPv4(192, 168, 1, 5), "x", "*.cdn.example.com"),
+				NewHost(net.IPv4(192, 168, 1, 6), "cdn.example.com"),
+			),
+			WithMatcher(tc.matcher),
+			WithMatchPolicy(tc.policy),
+		).(*staticHosts)
+
+		if ips := hosts.LookupAll(tc.host); !equalIPs(ips, tc.ips) {
+			t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package hosts
+
+import (
+	"net"
+)
+
+// MultiHosts is a Hosts which queries an ordered list of Hosts,
+// the result of the first Hosts which has the queried entry is returned,
+// so that the earlier Hosts override the later ones.
+type MultiHosts struct {
+	hosts []Hosts
+}
+
+// NewMultiHosts creates a MultiHosts from an ordered list of Hosts.
+func NewMultiHosts(hosts ...Hosts) Hosts {
+	return &MultiHosts{
+		hosts: hosts,
+	}
+}
+
+// Lookup returns the first non-nil result of the Lookup of the Hosts.
+func (m *MultiHosts) Lookup(host string) net.IP {
+	for _, h := range m.hosts {
+		if ip := h.Lookup(host); ip != nil {
+			return ip
+		}
+	}
+	return nil
+}
+
+// LookupAll returns the first non-empty result of the LookupAll of the Hosts.
+func (m *MultiHosts) LookupAll(host string) []net.IP {
+	for _, h := range m.hosts {
+		if ips := h.LookupAll(host); len(ips) > 0 {
+			return ips
+		}
+	}
+	return nil
+}
+
+// ReverseLookup returns the first non-empty result of the ReverseLookup of the Hosts.
+func (m *MultiHosts) ReverseLookup(ip net.IP) []string {
+	for _, h := range m.hosts {
+		if names := h.ReverseLookup(ip); len(names) > 0 {
+			return names
+		}
+	}
+	return nil
+}
+
+// Exists checks whether any of the Hosts has an entry matching host.
+func (m *MultiHosts) Exists(host string) bool {
+	for _, h := range m.hosts {
+		if h.Exists(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasIP checks whether any of the Hosts has an entry with the IP address ip.
+func (m *MultiHosts) HasIP(ip net.IP) bool {
+	for _, h := range m.hosts {
+		if h.HasIP(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAll returns the entries of the Hosts which are Listers, in the order of the Hosts.
+func (m *MultiHosts) GetAll() []Host {
+	var hosts []Host
+	for _, h := range m.hosts {
+		if l, ok := h.(Lister); ok {
+			hosts = append(hosts, l.GetAll()...)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,54 @@
+package hosts
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestMultiHosts(t *testing.T) {
+	hosts := NewMultiHosts(
+		NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))),
+		NewHosts(WithInitialHosts(
+			NewHost(net.IPv4(192, 168, 2, 1), "example.com", "example"),
+			NewHost(net.IPv4(192, 168, 2, 2), "example.org"),
+			NewHost(net.IPv4(192, 168, 2, 3), "example.org"),
+		)),
+	)
+
+	var tests = []struct {
+		host string
+		ips  []net.IP
+	}{
+		{"example.com", []net.IP{net.IPv4(192, 168, 1, 1)}},
+		{"example", []net.IP{net.IPv4(192, 168, 2, 1)}},
+		{"example.org", []net.IP{net.IPv4(192, 168, 2, 2), net.IPv4(192, 168, 2, 3)}},
+		{"example.net", nil},
+	}
+	for i, tc := range tests {
+		var ip net.IP
+		if len(tc.ips) > 0 {
+			ip = tc.ips[0]
+		}
+		if v := hosts.Lookup(tc.host); !v.Equal(ip) {
+			t.Errorf("#%d test failed: lookup should be %s, got %s", i, ip, v)
+		}
+		if ips := hosts.LookupAll(tc.host); !equalIPs(ips, tc.ips) {
+			t.Errorf("#%d test failed: lookup should be %v, got %v", i, tc.ips, ips)
+		}
+	}
+
+	if names := hosts.ReverseLookup(net.IPv4(192, 168, 2, 1)); strings.Join(names, ",") != "example.com,example" {
+		t.Errorf("reverse lookup should be %v, got %v", []string{"example.com", "example"}, names)
+	}
+	if names := NewMultiHosts().ReverseLookup(net.IPv4(192, 168, 2, 1)); names != nil {
+		t.Errorf("reverse lookup should be %v, got %v", nil, names)
+	}
+
+	if !hosts.Exists("example") || hosts.Exists("example.net") {
+		t.Error("example should exist, example.net should not")
+	}
+	if !hosts.HasIP(net.IPv4(192, 168, 2, 3)) || hosts.HasIP(net.IPv4(192, 168, 2, 4)) {
+		t.Error("192.168.2.3 should exist, 192.168.2.4 should not")
+	}
+}
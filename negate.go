@@ -0,0 +1,53 @@
+package hosts
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// negationPrefix is the prefix of the negated names, an entry of !name makes name never match
+// a wildcard, a pattern, a cname option or the catch-all entry.
+const negationPrefix = "!"
+
+// isNegation checks whether name is a negated name.
+func isNegation(name string) bool {
+	return strings.HasPrefix(name, negationPrefix)
+}
+
+// parseNegations parses the fields of an entry of negated names, such as "!good.example.com".
+func (p *parser) parseNegations(ss []string) error {
+	for _, s := range ss {
+		name := strings.TrimPrefix(s, negationPrefix)
+		if !isNegation(s) || name == "" || isPattern(name) || strings.HasPrefix(name, "*") {
+			return fmt.Errorf("%w %q", ErrInvalidName, s)
+		}
+		if _, err := toASCII(name); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidName, s, err)
+		}
+	}
+
+	if p.negated == nil {
+		p.negated = make(map[string]string)
+	}
+	for _, s := range ss {
+		name := s[len(negationPrefix):]
+		p.negated[p.key(name)] = name
+	}
+	return nil
+}
+
+// writeNegations writes the entries of the negated names to b, sorted by the names.
+func writeNegations(b *bytes.Buffer, negated map[string]string) {
+	keys := make([]string, 0, len(negated))
+	for k := range negated {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(negationPrefix)
+		b.WriteString(negated[k])
+		b.WriteByte('\n')
+	}
+}
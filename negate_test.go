@@ -0,0 +1,94 @@
+package hosts
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+var hostsNegationTests = []struct {
+	host string
+	ip   net.IP
+}{
+	{"ads.example.com", net.IPv4(0, 0, 0, 0)},
+	{"good.example.com", nil},
+	{"GOOD.example.com:443", nil},
+	{"www.good.example.com", net.IPv4(0, 0, 0, 0)},
+	{"api.example.com", net.IPv4(192, 168, 1, 1)},
+	{"web", nil},
+	{"tracker1.example.org", nil},
+	{"tracker2.example.org", net.IPv4(0, 0, 0, 0)},
+	{"unknown", net.IPv4(10, 0, 0, 1)},
+	{"allowed", nil},
+}
+
+func TestHostsNegation(t *testing.T) {
+	config := `
+0.0.0.0 *.example.com
+!good.example.com
+192.168.1.1 api.example.com
+!api.example.com !web
+cname api.example.com web
+0.0.0.0 re:tracker\d\.example\.org
+!tracker1.example.org
+10.0.0.1 *
+!allowed
+`
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := hosts.Errors(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	check := func(hosts *staticHosts, state string) {
+		for i, tc := range hostsNegationTests {
+			if ip := hosts.Lookup(tc.host); !ip.Equal(tc.ip) {
+				t.Errorf("#%d test failed: lookup of %s should be %s %s, got %s", i, tc.host, tc.ip, state, ip)
+			}
+		}
+	}
+	check(hosts, "")
+
+	hosts.Add(NewHost(net.IPv4(192, 168, 1, 2), "example.org"))
+	check(hosts, "after add")
+
+	var b bytes.Buffer
+	if _, err := hosts.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	other := NewHosts().(*staticHosts)
+	if err := other.Reload(&b); err != nil {
+		t.Fatal(err)
+	}
+	check(other, "after round trip")
+
+	s := hosts.Snapshot()
+	hosts.Clear()
+	hosts.Restore(s)
+	check(hosts, "after restore")
+}
+
+var hostsNegationErrorsTests = []string{
+	"!",
+	"!good.example.com good",
+	"!*.example.com",
+	"!re:good",
+	"!-münchen.de",
+	"192.168.1.1 example.com !good",
+}
+
+func TestHostsNegationErrors(t *testing.T) {
+	for i, config := range hostsNegationErrorsTests {
+		hosts := NewHosts().(*staticHosts)
+		if err := hosts.Reload(strings.NewReader(config)); err != nil {
+			t.Fatal(err)
+		}
+		if errs := hosts.Errors(); len(errs) != 1 || !errors.Is(errs[0].Err, ErrInvalidName) {
+			t.Errorf("#%d test failed: error should be %v, got %v", i, ErrInvalidName, errs)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package hosts
+
+import (
+	"math/rand"
+	"net"
+	"os"
+	"time"
+)
+
+// Option configures a Hosts created by NewHosts.
+type Option func(opts *options)
+
+type options struct {
+	hosts         []Host
+	logger        Logger
+	caseSensitive bool
+	conflict      ConflictStrategy
+	expand        func(string) string
+	allowLoopback bool    // loopback addresses are not considered as blocked
+	matcher       Matcher // nil for the indexed lookup
+	matchPolicy   MatchPolicy
+	commentChar   byte
+	comments      bool                 // the trailing comments of the entries are retained
+	strict        bool                 // an invalid line fails the reload
+	less          func(a, b Host) bool // the order of the entries after a reload, nil to keep the config order
+	maxEntries    int                  // the maximum number of entries of a reload, 0 for unlimited
+	cacheSize     int                  // the size of the lookup cache, 0 to disable the cache
+	retry         RetryPolicy
+	tracer        Tracer // nil for no tracing
+	lookupTiming  bool   // the durations of the lookups are recorded in Stats
+	intn          func(n int) int
+	healthCheck   func(net.IP) bool // nil for no health check
+	underscore    bool              // the underscores are allowed in the names
+	separators    string            // the characters separating the fields, empty for spaces and tabs
+}
+
+func defaultOptions() options {
+	return options{
+		logger:      nopLogger{},
+		expand:      os.Getenv,
+		commentChar: '#',
+		intn:        rand.Intn,
+	}
+}
+
+// WithInitialHosts sets the initial entries of the host table.
+func WithInitialHosts(hosts ...Host) Option {
+	return func(opts *options) {
+		opts.hosts = append(opts.hosts, hosts...)
+	}
+}
+
+// WithLogger sets the logger of the diagnostics of reloading, a nil logger disables logging,
+// which is the default.
+func WithLogger(logger Logger) Option {
+	return func(opts *options) {
+		if logger == nil {
+			logger = nopLogger{}
+		}
+		opts.logger = logger
+	}
+}
+
+// WithCaseInsensitive sets whether the hostnames and aliases are matched case-insensitively,
+// which is the default. Internationalized names are always case-folded by their conversion to ASCII.
+func WithCaseInsensitive(b bool) Option {
+	return func(opts *options) {
+		opts.caseSensitive = !b
+	}
+}
+
+// WithConflictStrategy sets the strategy to resolve the names defined more than once,
+// it is ConflictFirstWins by default.
+func WithConflictStrategy(strategy ConflictStrategy) Option {
+	return func(opts *options) {
+		opts.conflict = strategy
+	}
+}
+
+// WithExpand sets the function to replace the variable references ($VAR or ${VAR}) in the config,
+// it is os.Getenv by default. A nil function disables the replacement.
+// The remote configs of ReloadURL are never replaced.
+// The comments are removed before the replacement, so a "#" in a variable value does not start a comment.
+func WithExpand(mapping func(string) string) Option {
+	return func(opts *options) {
+		opts.expand = mapping
+	}
+}
+
+// WithBlockLoopback sets whether the hosts resolved to a loopback address are considered as blocked by IsBlocked,
+// which is the default.
+func WithBlockLoopback(b bool) Option {
+	return func(opts *options) {
+		opts.allowLoopback = !b
+	}
+}
+
+// WithMatcher sets the matcher of the lookups, which replaces the default lookup of the exact names,
+// wildcards and patterns. The lookups scan the whole host table with a matcher,
+// the entries are selected by the policy set by WithMatchPolicy. A nil matcher restores the default lookup.
+func WithMatcher(m Matcher) Option {
+	return func(opts *options) {
+		opts.matcher = m
+	}
+}
+
+// WithMatchPolicy sets the way to select the entries matching a host by the matcher set by WithMatcher,
+// it is FirstMatch by default. The policy only applies to the lookups with a matcher,
+// the default lookup always selects the exact name, then the most specific wildcard, then the first pattern.
+func WithMatchPolicy(policy MatchPolicy) Option {
+	return func(opts *options) {
+		opts.matchPolicy = policy
+	}
+}
+
+// WithCommentChar sets the character starting a comment in the config, it is '#' by default.
+// Only the chosen character starts a comment.
+func WithCommentChar(c byte) Option {
+	return func(opts *options) {
+		opts.commentChar = c
+	}
+}
+
+// WithComments sets whether the trailing comments of the entries are retained in their Comment field,
+// and written back by WriteTo. The comments are dropped by default.
+func WithComments(b bool) Option {
+	return func(opts *options) {
+		opts.comments = b
+	}
+}
+
+// WithStrict makes a reload fail on the first invalid line, such as an invalid IP address,
+// too few fields or an invalid option, leaving the host table unchanged.
+// The returned error is a *ParseError reporting the line.
+// By default the invalid lines are skipped, and retrieved by Errors after the reload.
+func WithStrict() Option {
+	return func(opts *options) {
+		opts.strict = true
+	}
+}
+
+// WithSort sets the order of the entries after a reload, so that GetAll and WriteTo return them
+// in a deterministic order regardless of the order of the config. The entries are sorted stably by less,
+// or by hostname then IP if less is nil. The addresses of a host returned by LookupAll are in the sorted order too.
+// The entries added by Add or Set are appended as usual.
+func WithSort(less func(a, b Host) bool) Option {
+	return func(opts *options) {
+		if less == nil {
+			less = lessHost
+		}
+		opts.less = less
+	}
+}
+
+// WithMaxEntries limits the number of entries of a reload to n, a zero or negative n means unlimited,
+// which is the default. A reload exceeding the limit stops parsing and fails with a *ParseError
+// of ErrTooManyEntries reporting the line, leaving the host table unchanged.
+func WithMaxEntries(n int) Option {
+	return func(opts *options) {
+		if n < 0 {
+			n = 0
+		}
+		opts.maxEntries = n
+	}
+}
+
+// WithLookupCache enables a LRU cache of the results of the last size distinct lookups,
+// including the hosts not found, so that the repeated lookups of the same names skip
+// the matching of the wildcards, patterns and matchers. The cache is invalidated whenever the host table changes.
+// The cache is disabled by default, or if size is zero or negative.
+func WithLookupCache(size int) Option {
+	return func(opts *options) {
+		opts.cacheSize = size
+	}
+}
+
+// WithRetry sets the policy of retrying the failed reloads of Run, before waiting for the next reload period.
+// The zero fields of policy are set to the defaults: 3 attempts, a delay of 1s and a maximum delay of 30s.
+// The failed reloads are not retried by default.
+func WithRetry(policy RetryPolicy) Option {
+	return func(opts *options) {
+		if policy.Attempts <= 0 {
+			policy.Attempts = 3
+		}
+		if policy.Delay <= 0 {
+			policy.Delay = time.Second
+		}
+		if policy.MaxDelay == 0 {
+			policy.MaxDelay = 30 * time.Second
+		}
+		opts.retry = policy
+	}
+}
+
+// WithTracer sets the tracer starting a span for each lookup of LookupContext and Lookup.
+// The lookups are not traced by default.
+func WithTracer(tracer Tracer) Option {
+	return func(opts *options) {
+		opts.tracer = tracer
+	}
+}
+
+// WithLookupTiming sets whether the durations of the lookups are recorded in the LookupTime of Stats.
+// The lookups are not timed by default, so that they do not read the clock.
+func WithLookupTiming(b bool) Option {
+	return func(opts *options) {
+		opts.lookupTiming = b
+	}
+}
+
+// WithRandom sets the source of the random numbers of LookupWeighted, intn returns a number in [0, n)
+// and must be safe for concurrent use, such as for deterministic tests.
+// The source is rand.Intn by default, a nil intn restores it.
+func WithRandom(intn func(n int) int) Option {
+	return func(opts *options) {
+		if intn == nil {
+			intn = rand.Intn
+		}
+		opts.intn = intn
+	}
+}
+
+// WithHealthCheck sets the health check of the addresses, the addresses for which check returns false
+// are left out of the results of LookupAll and the lookups based on it, such as Lookup and LookupRoundRobin,
+// unless all the addresses of a host fail the check.
+// The result of checking an address is cached for 5s, check must be safe for concurrent use.
+// The addresses are not checked by default.
+func WithHealthCheck(check func(net.IP) bool) Option {
+	return func(opts *options) {
+		opts.healthCheck = check
+	}
+}
+
+// WithUnderscores sets whether the underscores are allowed in the labels of the names, such as my_host.internal,
+// which are rejected by the RFC 1123 rules checked by Reload and Add by default.
+func WithUnderscores(b bool) Option {
+	return func(opts *options) {
+		opts.underscore = b
+	}
+}
+
+// WithSeparators sets the characters separating the fields of the lines of the config instead of spaces and tabs,
+// such as ", \t" for the files using commas and blanks. Successive separators are a single one,
+// and a separator is part of a field if it is quoted or escaped by a backslash, which only suits the fields other than the names,
+// such as the paths of the include option, as a name with a separator is not a valid domain name.
+// The fields are separated by spaces and tabs by default, or if seps is empty.
+// WriteTo separates the fields by the first of seps, so that its output can be reloaded.
+func WithSeparators(seps string) Option {
+	return func(opts *options) {
+		opts.separators = seps
+	}
+}
+
+// fieldSeparator returns the separator of the fields written by WriteTo, the first of the separators or a space.
+func (opts *options) fieldSeparator() byte {
+	if opts.separators != "" {
+		return opts.separators[0]
+	}
+	return ' '
+}
+
+// key returns the function to convert the names to their keys in the index.
+func (opts *options) key() func(string) string {
+	if opts.caseSensitive {
+		return normalizeCase
+	}
+	return normalize
+}
@@ -0,0 +1,643 @@
+package hosts
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	// ErrTooFewFields is reported for a line without enough fields for an entry or option.
+	ErrTooFewFields = errors.New("too few fields")
+	// ErrInvalidIP is reported for an entry with an invalid IP address.
+	ErrInvalidIP = errors.New("invalid IP address")
+	// ErrInvalidName is reported for an entry with a hostname or alias
+	// which is not a valid internationalized domain name.
+	ErrInvalidName = errors.New("invalid name")
+	// ErrInvalidPattern is reported for an entry with a pattern name (re:expr)
+	// whose expression is not a valid regular expression.
+	ErrInvalidPattern = errors.New("invalid pattern")
+	// ErrIncludeCycle is reported for an include option which includes one of its including files.
+	ErrIncludeCycle = errors.New("include cycle")
+	// ErrIncludeDepth is reported for an include option nested too deeply.
+	ErrIncludeDepth = errors.New("include nested too deeply")
+	// ErrIncludeRemote is reported for an include option in a remote config, such as the one of ReloadURL.
+	ErrIncludeRemote = errors.New("include of a remote config")
+	// ErrTooManyEntries is the error of a reload exceeding the limit set by WithMaxEntries.
+	ErrTooManyEntries = errors.New("too many entries")
+	// ErrInvalidWeight is reported for an address with a weight which is not a positive integer.
+	ErrInvalidWeight = errors.New("invalid weight")
+	// ErrInvalidTag is reported for a tag which cannot be written in the hosts config, such as a tag of a JSON or YAML config.
+	ErrInvalidTag = errors.New("invalid tag")
+)
+
+// weightPrefix is the prefix of the weight of an address, e.g. 10.0.0.1#w=3.
+const weightPrefix = "#w="
+
+// maxIncludeDepth is the maximum nesting depth of the include option.
+const maxIncludeDepth = 8
+
+// ParseError is an invalid line of the hosts config.
+type ParseError struct {
+	File string // the file containing the line, empty if the config is not read from a file
+	Line int    // 1-based line number
+	Text string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s: line %d: %v: %q", e.File, e.Line, e.Err, e.Text)
+	}
+	return fmt.Sprintf("line %d: %v: %q", e.Line, e.Err, e.Text)
+}
+
+// Unwrap returns the cause of the error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors is a list of invalid lines of the hosts config.
+type ParseErrors []ParseError
+
+func (e ParseErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d invalid lines:", len(e))
+	for i := range e {
+		b.WriteString("\n\t")
+		b.WriteString(e[i].Error())
+	}
+	return b.String()
+}
+
+// parser parses the hosts config.
+type parser struct {
+	period time.Duration
+	reload bool // the config has a reload option, which replaces the period of the host table
+	ttl    time.Duration
+	now    time.Time
+	hosts  []Host
+	nets   []netHost
+	errs   []ParseError
+
+	file     string          // the file being parsed
+	line     int             // number of the line being parsed
+	text     string          // text of the line being parsed
+	depth    int             // nesting depth of the file being parsed
+	visiting map[string]bool // absolute paths of the files being parsed
+
+	key        func(string) string
+	expand     func(string) string
+	remote     bool // the config is not trusted, the include option is refused and the variables are not expanded
+	conflict   ConflictStrategy
+	cchar      byte                 // the comment character
+	comments   bool                 // the trailing comments are retained
+	comment    string               // the trailing comment of the entry being parsed
+	tags       map[string]string    // the tags of the entry being parsed
+	strict     bool                 // an invalid line aborts the parsing
+	fields     []string             // the buffer of the fields of the line being parsed
+	index      *index               // the index of hosts, built as the hosts are parsed
+	stale      bool                 // the aliases of the indexed hosts are changed
+	defs       map[string]*nameDefs // the definitions of the names
+	dropped    map[int]bool         // indexes of the hosts overridden by later definitions
+	entries    map[string]int       // indexes of the hosts by their entry keys, to drop the identical entries
+	dups       []ParseError         // the duplicate names found
+	err        error                // the error aborting the parsing
+	sum        hash.Hash64          // checksum of the parsed config
+	cnames     map[string]cname     // the aliases defined by the cname option, by their keys
+	negated    map[string]string    // the negated names by their keys
+	less       func(a, b Host) bool // the order of the hosts, nil to keep the parsed order
+	max        int                  // the maximum number of hosts, 0 for unlimited
+	underscore bool                 // the underscores are allowed in the labels of the names
+	seps       string               // the characters separating the fields, empty for spaces and tabs
+}
+
+// newParser creates a parser with the options of h.
+func (h *staticHosts) newParser() *parser {
+	return &parser{
+		now:        time.Now(),
+		visiting:   make(map[string]bool),
+		key:        h.key,
+		expand:     h.options.expand,
+		conflict:   h.options.conflict,
+		cchar:      h.options.commentChar,
+		comments:   h.options.comments,
+		strict:     h.options.strict,
+		less:       h.options.less,
+		max:        h.options.maxEntries,
+		underscore: h.options.underscore,
+		seps:       h.options.separators,
+		defs:       make(map[string]*nameDefs),
+		dropped:    make(map[int]bool),
+		entries:    make(map[string]int),
+		index:      newIndex(h.key),
+		sum:        fnv.New64a(),
+	}
+}
+
+// setRemote makes p parse a remote config, which must not read the local files or the environment.
+func (p *parser) setRemote() {
+	p.remote = true
+	p.expand = nil
+}
+
+// hash adds the text s of the config to the checksum.
+func (p *parser) hash(s string) {
+	io.WriteString(p.sum, s)
+	p.sum.Write([]byte{'\n'})
+}
+
+// checksum returns the checksum of the parsed config,
+// or nil if the result depends on the time of the parsing, that is, any entry has a TTL.
+func (p *parser) checksum() []byte {
+	for _, host := range p.hosts {
+		if host.TTL != 0 {
+			return nil
+		}
+	}
+	return p.sum.Sum(nil)
+}
+
+// parse parses config from r, invalid lines are collected in p.errs.
+// An error is returned if r can not be read or the parsing is aborted.
+func (p *parser) parse(r io.Reader) error {
+	if n := sizeHint(r); n > 0 {
+		p.grow(int(n / avgLineSize))
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		p.line, p.text = n, line
+		if err := p.parseLine(line); err != nil {
+			p.report(ParseError{File: p.file, Line: n, Text: line, Err: err})
+		}
+		if p.err != nil {
+			return p.err
+		}
+	}
+	return scanner.Err()
+}
+
+// report collects the invalid line e, or aborts the parsing with e in strict mode.
+func (p *parser) report(e ParseError) {
+	if !p.strict {
+		p.errs = append(p.errs, e)
+		return
+	}
+	if p.err == nil {
+		p.err = &e
+	}
+}
+
+// finish removes the overridden hosts and sorts the hosts, it must be called after the parsing.
+// The hosts are removed in place, and the index is rebuilt if any indexed host is changed.
+func (p *parser) finish() {
+	if len(p.dropped) > 0 {
+		hosts := p.hosts[:0]
+		for i, host := range p.hosts {
+			if !p.dropped[i] {
+				hosts = append(hosts, host)
+			}
+		}
+		for i := len(hosts); i < len(p.hosts); i++ {
+			p.hosts[i] = Host{}
+		}
+		p.hosts = hosts
+	}
+	if cap(p.hosts) > 2*len(p.hosts)+avgLineSize {
+		// the size hint overestimated the entries, such as for a config mostly of comments.
+		hosts := make([]Host, len(p.hosts))
+		copy(hosts, p.hosts)
+		p.hosts = hosts
+	}
+	if p.less != nil {
+		sort.SliceStable(p.hosts, func(i, j int) bool {
+			return p.less(p.hosts[i], p.hosts[j])
+		})
+	}
+	if len(p.dropped) > 0 || p.stale || p.less != nil {
+		p.index = buildIndex(p.hosts, p.key)
+	}
+}
+
+// avgLineSize is the estimated average size of the lines of the config,
+// used to estimate the number of entries from the size of the config.
+const avgLineSize = 32
+
+// grow grows the capacity of p.hosts for n more entries.
+func (p *parser) grow(n int) {
+	if p.max > 0 && n > p.max {
+		n = p.max
+	}
+	if n <= cap(p.hosts)-len(p.hosts) {
+		return
+	}
+	hosts := make([]Host, len(p.hosts), len(p.hosts)+n)
+	copy(hosts, p.hosts)
+	p.hosts = hosts
+}
+
+// sizeHint returns the size of the content of r if it is known, otherwise zero.
+func sizeHint(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }: // bytes.Buffer, bytes.Reader and strings.Reader
+		return int64(v.Len())
+	case *os.File:
+		if fi, err := v.Stat(); err == nil && fi.Mode().IsRegular() {
+			return fi.Size()
+		}
+	}
+	return 0
+}
+
+// parseFile parses config from the file at path.
+func (p *parser) parseFile(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if p.visiting[abs] {
+		return ErrIncludeCycle
+	}
+	if p.depth >= maxIncludeDepth {
+		return ErrIncludeDepth
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	file := p.file
+	p.file = path
+	p.depth++
+	p.hash(abs)
+	p.visiting[abs] = true
+	defer func() {
+		p.file = file
+		p.depth--
+		delete(p.visiting, abs)
+	}()
+
+	return p.parse(f)
+}
+
+func (p *parser) parseLine(line string) error {
+	line, tags := splitTags(line, p.cchar)
+	line, comment := splitComment(line, p.cchar)
+	if !p.comments {
+		comment = ""
+	}
+	if p.expand != nil && strings.IndexByte(line, '$') >= 0 {
+		line = os.Expand(line, p.expand)
+	}
+	ss := appendLineFields(p.fields[:0], line, p.seps)
+	p.fields = ss
+	if len(ss) == 0 {
+		return nil // empty lines and comments
+	}
+	p.hash(strings.Join(ss, " "))
+	if comment != "" {
+		p.hash(comment)
+	}
+	if tags != nil {
+		p.hash(formatTags(tags, p.cchar))
+	}
+	if isNegation(ss[0]) {
+		return p.parseNegations(ss)
+	}
+	if len(ss) < 2 {
+		return ErrTooFewFields
+	}
+
+	switch ss[0] {
+	case "reload": // reload option
+		period, err := time.ParseDuration(ss[1])
+		if err != nil {
+			return err
+		}
+		p.period, p.reload = period, true
+	case "ttl": // ttl option
+		ttl, err := time.ParseDuration(ss[1])
+		if err != nil {
+			return err
+		}
+		p.ttl = ttl
+	case "cname": // cname option
+		return p.parseCNAME(ss[1:])
+	case "include": // include option
+		if p.remote {
+			return ErrIncludeRemote
+		}
+		path := ss[1]
+		if !filepath.IsAbs(path) && p.file != "" {
+			path = filepath.Join(filepath.Dir(p.file), path)
+		}
+		return p.parseFile(path)
+	default:
+		p.comment, p.tags = comment, tags
+		return p.parseHosts(ss)
+	}
+	return nil
+}
+
+// parseHosts parses the fields of an entry, the fields which are IP addresses are the addresses of the entry,
+// the fields which are CIDR notations are the networks of the entry,
+// the first of the other fields is the hostname, and the rest are the aliases.
+func (p *parser) parseHosts(ss []string) error {
+	var ips []net.IPAddr
+	var weights []int
+	var nets []*net.IPNet
+	var names []string
+	for _, s := range ss {
+		s, weight, err := splitWeight(s)
+		if err != nil {
+			return err
+		}
+		if ip, zone := parseIPZone(s); ip != nil {
+			ips = append(ips, net.IPAddr{IP: ip, Zone: zone})
+			weights = append(weights, weight)
+			continue
+		}
+		if weight != 0 {
+			return fmt.Errorf("%w %q: weight of a name", ErrInvalidWeight, s)
+		}
+		if strings.IndexByte(s, '/') >= 0 {
+			if _, ipNet, err := net.ParseCIDR(s); err == nil {
+				nets = append(nets, ipNet)
+				continue
+			}
+		}
+		names = append(names, s)
+	}
+	if len(ips) == 0 && len(nets) == 0 {
+		return ErrInvalidIP
+	}
+	if len(nets) > 0 {
+		if err := p.addNets(nets, names); err != nil {
+			return err
+		}
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+	return p.addHosts(ips, weights, names, p.ttl)
+}
+
+// splitWeight splits the field s into the address and its weight, zero if s has no weight.
+func splitWeight(s string) (string, int, error) {
+	n := strings.Index(s, weightPrefix)
+	if n < 0 {
+		return s, 0, nil
+	}
+	w, err := strconv.Atoi(s[n+len(weightPrefix):])
+	if err != nil || w <= 0 {
+		return s, 0, fmt.Errorf("%w %q", ErrInvalidWeight, s)
+	}
+	return s[:n], w, nil
+}
+
+// addNets adds a network entry for each network in nets, names are the names of the networks.
+func (p *parser) addNets(nets []*net.IPNet, names []string) error {
+	if err := p.checkNames(names); err != nil {
+		return err
+	}
+	for _, ipNet := range nets {
+		p.nets = append(p.nets, netHost{Net: ipNet, Names: names})
+	}
+	return nil
+}
+
+// addHosts adds an entry for each address in ips, names are the hostname followed by the aliases.
+// weights are the weights of the addresses, if not nil.
+func (p *parser) addHosts(ips []net.IPAddr, weights []int, names []string, ttl time.Duration) error {
+	if err := p.checkNames(names); err != nil {
+		return err
+	}
+
+	start := len(p.hosts)
+	for i, ip := range ips {
+		host := Host{
+			IP:       canonicalIP(ip.IP),
+			Zone:     ip.Zone,
+			Hostname: names[0],
+			TTL:      ttl,
+			Comment:  p.comment,
+			Tags:     p.tags,
+			Source:   p.file,
+		}
+		if len(names) > 1 {
+			host.Aliases = names[1:]
+		}
+		if weights != nil {
+			host.Weight = weights[i]
+		}
+		if p.duplicate(host) {
+			continue
+		}
+		if p.max > 0 && len(p.hosts)-len(p.dropped) >= p.max {
+			p.err = &ParseError{File: p.file, Line: p.line, Text: p.text, Err: ErrTooManyEntries}
+			return nil
+		}
+		p.hosts = append(p.hosts, host.withExpires(p.now))
+		p.index.addHost(host, len(p.hosts)-1)
+	}
+	if start < len(p.hosts) {
+		p.define(names, start, len(p.hosts))
+	}
+	return nil
+}
+
+// canonicalIP returns the 4-byte form of ip if it is an IPv4 or IPv4-mapped IPv6 address,
+// so that ::ffff:192.168.0.1 and 192.168.0.1 are the same entry.
+func canonicalIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// duplicate checks whether an entry identical to host, with the same IP, hostname and aliases, is already parsed,
+// otherwise host is recorded as the entry to be added next.
+func (p *parser) duplicate(host Host) bool {
+	k := p.entryKey(host)
+	if i, ok := p.entries[k]; ok && !p.dropped[i] && p.entryKey(p.hosts[i]) == k {
+		return true
+	}
+	p.entries[k] = len(p.hosts)
+	return false
+}
+
+// entryKey returns the key of the IP, hostname and aliases of host.
+func (p *parser) entryKey(host Host) string {
+	var b strings.Builder
+	b.WriteString(ipKey(host.IP))
+	b.WriteString(host.Zone)
+	for _, name := range hostNames(host, p.key) {
+		b.WriteByte(0)
+		b.WriteString(name)
+	}
+	return b.String()
+}
+
+// checkNames checks the names of an entry, they must not be empty,
+// and must be valid patterns, or internationalized domain names per RFC 1123 like the ones of Host.Validate,
+// with the underscores allowed by WithUnderscores.
+func (p *parser) checkNames(names []string) error {
+	if len(names) == 0 {
+		return ErrTooFewFields
+	}
+	for _, name := range names {
+		if isNegation(name) {
+			return fmt.Errorf("%w %q: negated name of an entry", ErrInvalidName, name)
+		}
+		if err := validateName(name, p.underscore); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitLine splits a line text by white space, mainly used by config parser.
+// Text from a "#" character until the end of the line is ignored.
+func splitLine(line string) []string {
+	return splitFields(stripComment(line))
+}
+
+// stripComment removes the text from a "#" character until the end of the line.
+func stripComment(line string) string {
+	line, _ = splitComment(line, '#')
+	return line
+}
+
+// splitComment splits line at the comment character c,
+// the comment is returned without c and the surrounding white space.
+// A "#" character starting the weight of an address, e.g. 10.0.0.1#w=3, or in a quoted field does not start a comment.
+func splitComment(line string, c byte) (string, string) {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\'):
+			i++
+			continue
+		case line[i] == '"':
+			quoted = !quoted
+			continue
+		case line[i] != c || quoted:
+			continue
+		}
+		if c == weightPrefix[0] && i > 0 && line[i-1] != ' ' && line[i-1] != '\t' &&
+			strings.HasPrefix(line[i:], weightPrefix) {
+			continue
+		}
+		return line[:i], strings.TrimSpace(line[i+1:])
+	}
+	return line, ""
+}
+
+// splitFields splits a line text by white space.
+func splitFields(line string) []string {
+	return appendLineFields(nil, line, "")
+}
+
+// appendLineFields appends the fields of line separated by any of the characters of seps, or by spaces and tabs if seps is empty, to dst.
+// The lines without quotes or backslashes separated by spaces and tabs, as most of them, take the fast path of appendFields.
+func appendLineFields(dst []string, line, seps string) []string {
+	if seps == "" {
+		if strings.IndexAny(line, `"\`) < 0 {
+			return appendFields(dst, line)
+		}
+		seps = " \t"
+	}
+	return appendQuotedFields(dst, line, seps)
+}
+
+// appendFields appends the fields of line separated by spaces and tabs to dst,
+// the other white space around a field is trimmed. The line is scanned once,
+// so that no allocation is needed if dst has enough capacity.
+func appendFields(dst []string, line string) []string {
+	for i := 0; i < len(line); {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		j := i
+		for j < len(line) && line[j] != ' ' && line[j] != '\t' {
+			j++
+		}
+		if s := strings.TrimSpace(line[i:j]); s != "" {
+			dst = append(dst, s)
+		}
+		i = j
+	}
+	return dst
+}
+
+// appendQuotedFields appends the fields of line separated by any of the characters of seps to dst,
+// the white space around an unquoted field is trimmed.
+// A field may be quoted, e.g. "a b" or a" "b, to contain the separators,
+// and a backslash makes the following separator, quote or backslash literal.
+// The names are checked after the splitting, so a quoted name with a separator is still invalid.
+func appendQuotedFields(dst []string, line, seps string) []string {
+	var b strings.Builder
+	quoted := false
+	start, end := -1, 0 // the quoted or escaped part of the field being scanned, which is not trimmed
+	literal := func() {
+		if start < 0 {
+			start = b.Len()
+		}
+		end = b.Len()
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\' || strings.IndexByte(seps, line[i+1]) >= 0):
+			i++
+			literal()
+			b.WriteByte(line[i])
+			end++
+		case c == '"':
+			quoted = !quoted
+			literal()
+		case quoted:
+			b.WriteByte(c)
+			end++
+		case strings.IndexByte(seps, c) >= 0:
+			dst = appendField(dst, b.String(), start, end)
+			b.Reset()
+			start = -1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return appendField(dst, b.String(), start, end)
+}
+
+// appendField appends the non-empty field s to dst, trimming the white space around s
+// outside of its literal part s[start:end], if start is not negative.
+func appendField(dst []string, s string, start, end int) []string {
+	if start < 0 {
+		s = strings.TrimSpace(s)
+	} else {
+		s = strings.TrimLeftFunc(s[:start], unicode.IsSpace) + s[start:end] + strings.TrimRightFunc(s[end:], unicode.IsSpace)
+	}
+	if s == "" {
+		return dst
+	}
+	return append(dst, s)
+}
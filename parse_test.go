@@ -0,0 +1,575 @@
+package hosts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var hostsErrorsTests = []struct {
+	r     string
+	lines []int
+	errs  []error
+}{
+	{"", nil, nil},
+	{"# comment\n\n192.168.1.1 example.com", nil, nil},
+	{"example.com", []int{1}, []error{ErrTooFewFields}},
+	{"192.168.1.1 example.com\nfoo example.com", []int{2}, []error{ErrInvalidIP}},
+	{"192.168.1.1 example.com -münchen.de\n\n192.168.1.2", []int{1, 3}, []error{ErrInvalidName, ErrTooFewFields}},
+	{"0.0.0.0 re:ads(\n0.0.0.0 re:ads\\d", []int{1}, []error{ErrInvalidPattern}},
+	{"0.0.0.0 glob:ads[\n0.0.0.0 glob:ads*", []int{1}, []error{ErrInvalidPattern}},
+	{"192.168.1.1 192.168.1.2\nexample.com example", []int{1, 2}, []error{ErrTooFewFields, ErrInvalidIP}},
+	{"reload 10s\nreload foo", []int{2}, []error{nil}},
+	{"192.168.1.1 foo_bar.com\n192.168.1.2 example.com foo..bar\n192.168.1.3 a\x01b", []int{1, 2, 3}, []error{ErrInvalidName, ErrInvalidName, ErrInvalidName}},
+	{"192.168.1.1 * *.example.com example.com.", nil, nil},
+}
+
+func TestHostsErrors(t *testing.T) {
+	for i, tc := range hostsErrorsTests {
+		hosts := NewHosts().(*staticHosts)
+		if err := hosts.Reload(strings.NewReader(tc.r)); err != nil {
+			t.Error(err)
+		}
+		errs := hosts.Errors()
+		if len(errs) != len(tc.lines) {
+			t.Errorf("#%d test failed: errors should be %v, got %v", i, tc.errs, errs)
+			continue
+		}
+		for j, e := range errs {
+			if e.Line != tc.lines[j] || tc.errs[j] != nil && !errors.Is(e.Err, tc.errs[j]) {
+				t.Errorf("#%d test failed: error should be line %d: %v, got %v", i, tc.lines[j], tc.errs[j], &e)
+			}
+		}
+	}
+}
+
+func TestHostsReloadUnderscores(t *testing.T) {
+	config := "192.168.1.1 my_host.internal\n192.168.1.2 example.com"
+
+	hosts := NewHosts(WithStrict()).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(config)); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("strict reload should fail with %v, got %v", ErrInvalidName, err)
+	}
+
+	hosts = NewHosts(WithStrict(), WithUnderscores(true)).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("my_host.internal"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+	if err := hosts.Add(NewHost(net.IPv4(192, 168, 1, 3), "other_host.internal")); err != nil {
+		t.Errorf("add should allow the underscores, got %v", err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	err := &ParseError{Line: 3, Text: "foo example.com", Err: ErrInvalidIP}
+	if v, expected := err.Error(), `line 3: invalid IP address: "foo example.com"`; v != expected {
+		t.Errorf("error should be %s, got %s", expected, v)
+	}
+}
+
+func TestHostsReloadFileInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"hosts":     "192.168.1.1 example.com\ninclude hosts.d/a\ninclude missing\n192.168.1.4 example.com",
+		"hosts.d/a": "192.168.1.2 example.com\ninclude b\n",
+		"hosts.d/b": "192.168.1.3 example.com\ninclude ../hosts\n",
+		"cycle":     "include cycle",
+		"deep":      "include deep.d/1",
+		"deep.d/1":  "include 2\n192.168.1.1 deep.example.com",
+		"deep.d/2":  "include 3",
+		"deep.d/3":  "include 4",
+		"deep.d/4":  "include 5",
+		"deep.d/5":  "include 6",
+		"deep.d/6":  "include 7",
+		"deep.d/7":  "include 8",
+		"deep.d/8":  "include 9",
+		"deep.d/9":  "192.168.1.2 deep.example.com",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.ReloadFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("reload should fail for a missing file")
+	}
+
+	if err := hosts.ReloadFile(filepath.Join(dir, "hosts")); err != nil {
+		t.Fatal(err)
+	}
+	ips := hosts.LookupAll("example.com")
+	expected := []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2), net.IPv4(192, 168, 1, 3), net.IPv4(192, 168, 1, 4)}
+	if len(ips) != len(expected) {
+		t.Fatalf("lookup should be %v, got %v", expected, ips)
+	}
+	for i := range ips {
+		if !ips[i].Equal(expected[i]) {
+			t.Fatalf("lookup should be %v, got %v", expected, ips)
+		}
+	}
+	errs := hosts.Errors()
+	if len(errs) != 2 ||
+		errs[0].File != filepath.Join(dir, "hosts.d/b") || errs[0].Line != 2 || errs[0].Err != ErrIncludeCycle ||
+		errs[1].File != filepath.Join(dir, "hosts") || errs[1].Line != 3 || !os.IsNotExist(errs[1].Err) {
+		t.Errorf("errors should be an include cycle and a missing file, got %v", errs)
+	}
+	sources := []string{"hosts", "hosts.d/a", "hosts.d/b", "hosts"}
+	for i, host := range hosts.GetAll() {
+		if source := filepath.Join(dir, sources[i]); host.Source != source {
+			t.Errorf("#%d test failed: source should be %s, got %s", i, source, host.Source)
+		}
+	}
+
+	if err := hosts.ReloadFile(filepath.Join(dir, "cycle")); err != nil {
+		t.Fatal(err)
+	}
+	if errs := hosts.Errors(); len(errs) != 1 || errs[0].Err != ErrIncludeCycle {
+		t.Errorf("errors should be an include cycle, got %v", errs)
+	}
+
+	if err := hosts.ReloadFile(filepath.Join(dir, "deep")); err != nil {
+		t.Fatal(err)
+	}
+	if ips := hosts.LookupAll("deep.example.com"); len(ips) != 1 || !ips[0].Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %v", net.IPv4(192, 168, 1, 1), ips)
+	}
+	if errs := hosts.Errors(); len(errs) != 1 || errs[0].Err != ErrIncludeDepth {
+		t.Errorf("errors should be an include depth, got %v", errs)
+	}
+}
+
+func TestHostsValidate(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(10, 0, 0, 1), "example.com"))).(*staticHosts)
+
+	if err := hosts.Validate(strings.NewReader("reload 10s\n192.168.1.1 example.com")); err != nil {
+		t.Errorf("validate should succeed, got %v", err)
+	}
+
+	err := hosts.Validate(strings.NewReader("192.168.1.1 example.com\nfoo example.com\nexample.com"))
+	errs, ok := err.(ParseErrors)
+	if !ok || len(errs) != 2 || errs[0].Line != 2 || errs[1].Line != 3 {
+		t.Fatalf("errors should be at line 2 and 3, got %v", err)
+	}
+	expected := "2 invalid lines:\n" +
+		"\tline 2: invalid IP address: \"foo example.com\"\n" +
+		"\tline 3: too few fields: \"example.com\""
+	if s := err.Error(); s != expected {
+		t.Errorf("error should be %q, got %q", expected, s)
+	}
+
+	if err := hosts.Validate(errReader{}); err == nil {
+		t.Error("validate should fail")
+	}
+	if err := hosts.Validate(nil); err != nil {
+		t.Errorf("validate of a nil reader should succeed like reload, got %v", err)
+	}
+
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(10, 0, 0, 1), ip)
+	}
+}
+
+func TestHostsReloadExpand(t *testing.T) {
+	os.Setenv("HOSTS_TEST_IP", "192.168.1.1")
+	os.Setenv("HOSTS_TEST_ALIAS", "example#1")
+	defer os.Unsetenv("HOSTS_TEST_IP")
+	defer os.Unsetenv("HOSTS_TEST_ALIAS")
+
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("${HOSTS_TEST_IP} example.com $HOSTS_TEST_ALIAS # $HOSTS_TEST_IP")); err != nil {
+		t.Fatal(err)
+	}
+	// the expanded "#" does not start a comment, so the alias is an invalid name
+	if errs := hosts.Errors(); len(errs) != 1 || !errors.Is(errs[0].Err, ErrInvalidName) {
+		t.Errorf("errors should be an invalid name, got %v", errs)
+	}
+
+	hosts = NewHosts(WithExpand(func(name string) string {
+		if name == "APP_IP" {
+			return "192.168.1.2"
+		}
+		return ""
+	})).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("${APP_IP} app.internal")); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("app.internal"); !ip.Equal(net.IPv4(192, 168, 1, 2)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 2), ip)
+	}
+
+	hosts = NewHosts(WithExpand(nil)).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("${APP_IP} app.internal")); err != nil {
+		t.Fatal(err)
+	}
+	if errs := hosts.Errors(); len(errs) != 1 || errs[0].Err != ErrInvalidIP {
+		t.Errorf("errors should be an invalid IP, got %v", errs)
+	}
+}
+
+var hostsStrictTests = []struct {
+	r    string
+	line int
+	err  error
+}{
+	{"192.168.1.2 example.com\n\n192.168.1.3 example.org", 0, nil},
+	{"192.168.1.2 example.com\nfoo example.org\nexample.net", 2, ErrInvalidIP},
+	{"192.168.1.2 example.com\nexample.org", 2, ErrTooFewFields},
+	{"reload foo\n192.168.1.2 example.com", 1, nil},
+	{"192.168.1.2 example.com\ninclude /nonexistent/hosts", 2, nil},
+}
+
+func TestHostsStrict(t *testing.T) {
+	for i, tc := range hostsStrictTests {
+		hosts := NewHosts(
+			WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com")),
+			WithStrict(),
+		).(*staticHosts)
+
+		err := hosts.Reload(strings.NewReader(tc.r))
+		if tc.line == 0 {
+			if err != nil {
+				t.Errorf("#%d test failed: error should be nil, got %v", i, err)
+			}
+			if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 2)) {
+				t.Errorf("#%d test failed: lookup should be %s, got %s", i, net.IPv4(192, 168, 1, 2), ip)
+			}
+			continue
+		}
+
+		var e *ParseError
+		if !errors.As(err, &e) || e.Line != tc.line || tc.err != nil && !errors.Is(err, tc.err) {
+			t.Errorf("#%d test failed: error should be line %d: %v, got %v", i, tc.line, tc.err, err)
+		}
+		if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+			t.Errorf("#%d test failed: host table should be unchanged, got %s", i, ip)
+		}
+		if errs := hosts.Errors(); len(errs) != 0 {
+			t.Errorf("#%d test failed: errors should be empty, got %v", i, errs)
+		}
+	}
+}
+
+func TestHostsReloadDedup(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 100; i++ {
+		b.WriteString("0.0.0.0 ads.example.com\n")
+		b.WriteString("192.168.1.1 example.com example\n")
+	}
+	b.WriteString("192.168.1.1 example.com\n")
+	b.WriteString("192.168.1.1 example example.com\n")
+
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(b.String())); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Host{
+		NewHost(net.IPv4(0, 0, 0, 0), "ads.example.com"),
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"),
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com"),
+		NewHost(net.IPv4(192, 168, 1, 1), "example", "example.com"),
+	}
+	all := hosts.GetAll()
+	if len(all) != len(expected) {
+		t.Fatalf("hosts should be %v, got %v", expected, all)
+	}
+	for i := range all {
+		if all[i].String() != expected[i].String() {
+			t.Errorf("#%d test failed: host should be %v, got %v", i, expected[i], all[i])
+		}
+	}
+}
+
+func TestHostsReloadMapped(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("::ffff:192.168.0.1 example.com\n192.168.0.1 example.com\n192.168.0.1 example")); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := hosts.Count(); n != 2 {
+		t.Errorf("count should be 2, got %d", n)
+	}
+	ips := hosts.LookupAll("example.com")
+	if len(ips) != 1 || len(ips[0]) != net.IPv4len || !ips[0].Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Errorf("addresses should be [192.168.0.1], got %v", ips)
+	}
+	for _, ip := range []net.IP{net.ParseIP("::ffff:192.168.0.1"), net.IPv4(192, 168, 0, 1).To4()} {
+		names := hosts.ReverseLookup(ip)
+		if len(names) != 2 || names[0] != "example.com" || names[1] != "example" {
+			t.Errorf("names of %s should be [example.com example], got %v", ip, names)
+		}
+	}
+}
+
+var splitLineTests = []struct {
+	line   string
+	fields []string
+}{
+	{"", nil},
+	{"   \t ", nil},
+	{"# comment", nil},
+	{"192.168.1.1 example.com", []string{"192.168.1.1", "example.com"}},
+	{"  192.168.1.1\t\texample.com  example ", []string{"192.168.1.1", "example.com", "example"}},
+	{"192.168.1.1 example.com # comment", []string{"192.168.1.1", "example.com"}},
+	{"192.168.1.1 example.com#comment", []string{"192.168.1.1", "example.com"}},
+	{"192.168.1.1 example.com\r", []string{"192.168.1.1", "example.com"}},
+	{"192.168.1.1 \r example.com", []string{"192.168.1.1", "example.com"}},
+	{"192.168.1.1\u00a0example.com", []string{"192.168.1.1\u00a0example.com"}},
+	{`192.168.1.1 "example.com" "my host"`, []string{"192.168.1.1", "example.com", "my host"}},
+	{`192.168.1.1 "a # b" # comment`, []string{"192.168.1.1", "a # b"}},
+	{`192.168.1.1 my\ host a\"b a\\b a\b`, []string{"192.168.1.1", "my host", `a"b`, `a\b`, `a\b`}},
+	{`192.168.1.1 ex"am ple".com "" ex\ `, []string{"192.168.1.1", "exam ple.com", "ex "}},
+	{"192.168.1.1 \" example \"\r", []string{"192.168.1.1", " example "}},
+}
+
+func TestSplitLine(t *testing.T) {
+	for i, tc := range splitLineTests {
+		fields := splitLine(tc.line)
+		if len(fields) != len(tc.fields) || strings.Join(fields, "|") != strings.Join(tc.fields, "|") {
+			t.Errorf("#%d test failed: fields should be %q, got %q", i, tc.fields, fields)
+		}
+	}
+}
+
+func TestHostsReloadSeparators(t *testing.T) {
+	hosts := NewHosts(WithSeparators(", \t")).(*staticHosts)
+	config := "192.168.1.1,example.com, example\n192.168.1.2 ,, example.org\texample.net\n192.168.1.3,\"example.io\"\n192.168.1.4,a\\,b.example.io"
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		ip   net.IP
+	}{
+		{"example.com", net.IPv4(192, 168, 1, 1)},
+		{"example", net.IPv4(192, 168, 1, 1)},
+		{"example.org", net.IPv4(192, 168, 1, 2)},
+		{"example.net", net.IPv4(192, 168, 1, 2)},
+		{"example.io", net.IPv4(192, 168, 1, 3)},
+	}
+	for i, tc := range tests {
+		if ip := hosts.Lookup(tc.host); !ip.Equal(tc.ip) {
+			t.Errorf("#%d test failed: lookup of %s should be %s, got %s", i, tc.host, tc.ip, ip)
+		}
+	}
+	// the escaped comma is part of the name, which is invalid
+	if errs := hosts.Errors(); len(errs) != 1 || errs[0].Line != 4 || !errors.Is(errs[0].Err, ErrInvalidName) {
+		t.Errorf("errors should be an invalid name at line 4, got %v", errs)
+	}
+}
+
+func TestHostsReloadQuotedInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "my hosts")
+	if err := ioutil.WriteFile(path, []byte("192.168.1.1 example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := NewHosts().(*staticHosts)
+	config := "include \"" + path + "\"\n" +
+		"192.168.1.2 \"example .org\"\n"
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+	// a quoted name with a space is not a valid domain name.
+	if errs := hosts.Errors(); len(errs) != 1 || errs[0].Line != 2 || !errors.Is(errs[0].Err, ErrInvalidName) {
+		t.Errorf("errors should be an invalid name at line 2, got %v", errs)
+	}
+}
+
+func BenchmarkSplitLine(b *testing.B) {
+	line := "192.168.1.1\texample.com  example www.example.com # comment"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		splitLine(line)
+	}
+}
+
+func BenchmarkAppendFields(b *testing.B) {
+	line := stripComment("192.168.1.1\texample.com  example www.example.com # comment")
+	var fields []string
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fields = appendFields(fields[:0], line)
+	}
+}
+
+// linesReader generates n entries without a size hint.
+type linesReader struct {
+	n, i int
+	buf  []byte
+}
+
+func (r *linesReader) Read(b []byte) (int, error) {
+	for len(r.buf) < len(b) && r.i < r.n {
+		r.buf = append(r.buf, fmt.Sprintf("10.%d.%d.%d host%d.example.com\n", r.i>>16&0xff, r.i>>8&0xff, r.i&0xff, r.i)...)
+		r.i++
+	}
+	if len(r.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func TestHostsReloadLarge(t *testing.T) {
+	const n = 100000
+
+	var b strings.Builder
+	io.Copy(&b, &linesReader{n: n})
+	config := b.String()
+
+	for i, r := range []io.Reader{&linesReader{n: n}, strings.NewReader(config)} {
+		hosts := NewHosts().(*staticHosts)
+		if err := hosts.Reload(r); err != nil {
+			t.Fatal(err)
+		}
+		if c := hosts.Count(); c != n {
+			t.Errorf("#%d test failed: count should be %d, got %d", i, n, c)
+		}
+		for _, k := range []int{0, n / 2, n - 1} {
+			host := fmt.Sprintf("host%d.example.com", k)
+			ip := net.IPv4(10, byte(k>>16), byte(k>>8), byte(k))
+			if v := hosts.Lookup(host); !v.Equal(ip) {
+				t.Errorf("#%d test failed: lookup of %s should be %s, got %s", i, host, ip, v)
+			}
+			if names := hosts.ReverseLookup(ip); len(names) != 1 || names[0] != host {
+				t.Errorf("#%d test failed: reverse lookup of %s should be %s, got %v", i, ip, host, names)
+			}
+		}
+	}
+
+	// the size hint presizes the entries.
+	p := NewHosts().(*staticHosts).newParser()
+	if err := p.parse(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if c := cap(p.hosts); c < n || c > 2*n {
+		t.Errorf("capacity should be close to %d, got %d", n, c)
+	}
+
+	// the overestimated entries of a config mostly of comments are released.
+	config = strings.Repeat("# "+strings.Repeat("comment ", 16)+"\n", n) + "192.168.1.1 example.com\n"
+	p = NewHosts().(*staticHosts).newParser()
+	if err := p.parse(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	p.finish()
+	if c := cap(p.hosts); c != 1 {
+		t.Errorf("capacity should be 1, got %d", c)
+	}
+}
+
+func TestParserIndex(t *testing.T) {
+	config := "192.168.1.1 example.com example\n" +
+		"192.168.1.2 example.org example\n" +
+		"192.168.1.3 example.com\n" +
+		"192.168.1.4 www.example.com"
+
+	for _, strategy := range []ConflictStrategy{ConflictFirstWins, ConflictLastWins} {
+		p := NewHosts(WithConflictStrategy(strategy)).(*staticHosts).newParser()
+		if err := p.parse(strings.NewReader(config)); err != nil {
+			t.Fatal(err)
+		}
+		p.finish()
+
+		idx := buildIndex(p.hosts, p.key)
+		for _, name := range []string{"example.com", "example", "example.org", "www.example.com"} {
+			if a, b := p.index.lookup(name), idx.lookup(name); fmt.Sprint(a) != fmt.Sprint(b) {
+				t.Errorf("strategy %d: index of %s should be %v, got %v", strategy, name, b, a)
+			}
+		}
+	}
+}
+
+func TestHostsReloadSort(t *testing.T) {
+	config := "192.168.1.2 b.example.com\n192.168.1.3 a.example.com\n::1 a.example.com\n192.168.1.1 c.example.com b"
+	var tests = []struct {
+		opt      Option
+		expected string
+	}{
+		{WithSort(nil), "::1         a.example.com\n192.168.1.3 a.example.com\n192.168.1.2 b.example.com\n192.168.1.1 c.example.com b\n"},
+		{WithSort(func(a, b Host) bool { return len(a.Aliases) > len(b.Aliases) }),
+			"192.168.1.1 c.example.com b\n192.168.1.2 b.example.com\n192.168.1.3 a.example.com\n::1         a.example.com\n"},
+	}
+	for i, tc := range tests {
+		hosts := NewHosts(tc.opt).(*staticHosts)
+		if err := hosts.Reload(strings.NewReader(config)); err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if _, err := hosts.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tc.expected {
+			t.Errorf("#%d test failed: config should be %q, got %q", i, tc.expected, b.String())
+		}
+		if ip := hosts.Lookup("b"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+			t.Errorf("#%d test failed: lookup should be %s, got %s", i, net.IPv4(192, 168, 1, 1), ip)
+		}
+	}
+}
+
+func TestHostsReloadMaxEntries(t *testing.T) {
+	hosts := NewHosts(WithMaxEntries(3)).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("192.168.1.1 a\n192.168.1.1 a\n192.168.1.2 192.168.1.3 b")); err != nil {
+		t.Fatal(err)
+	}
+	if n := hosts.Count(); n != 3 {
+		t.Fatalf("count should be 3, got %d", n)
+	}
+
+	err := hosts.Reload(strings.NewReader("192.168.2.1 a\n192.168.2.2 b\n192.168.2.3 c\n192.168.2.4 d\n192.168.2.5 e"))
+	var e *ParseError
+	if !errors.As(err, &e) || !errors.Is(err, ErrTooManyEntries) || e.Line != 4 {
+		t.Fatalf("error should be %v at line 4, got %v", ErrTooManyEntries, err)
+	}
+	if ip := hosts.Lookup("a"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+
+	b, _ := json.Marshal(map[string][]Host{"hosts": {NewHost(net.IPv4(192, 168, 3, 1), "a"), NewHost(net.IPv4(192, 168, 3, 2), "b"),
+		NewHost(net.IPv4(192, 168, 3, 3), "c"), NewHost(net.IPv4(192, 168, 3, 4), "d")}})
+	if err := hosts.ReloadJSON(bytes.NewReader(b)); !errors.Is(err, ErrTooManyEntries) {
+		t.Errorf("error should be %v, got %v", ErrTooManyEntries, err)
+	}
+
+	hosts = NewHosts(WithMaxEntries(0)).(*staticHosts)
+	if err := hosts.Reload(&linesReader{n: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if n := hosts.Count(); n != 1000 {
+		t.Errorf("count should be 1000, got %d", n)
+	}
+}
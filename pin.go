@@ -0,0 +1,58 @@
+package hosts
+
+import (
+	"time"
+)
+
+// Pin adds host to the host table as a pinned entry, which survives the reloads:
+// after each reload the pinned entries are merged on top of the parsed entries,
+// so that the names of a pinned entry override the ones of the config, like ConflictLastWins.
+// Pinning a hostname again for the same address family replaces its earlier pinned entry.
+// An error is returned for an invalid host like Add, and it is not pinned.
+func (h *staticHosts) Pin(host Host) error {
+	if err := host.validate(h.options.underscore); err != nil {
+		return err
+	}
+	host = host.clone().withExpires(time.Now())
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	pinned := make([]Host, 0, len(h.pinned)+1)
+	for _, p := range h.pinned {
+		if h.key(p.Hostname) != h.key(host.Hostname) || family(p.IP) != family(host.IP) {
+			pinned = append(pinned, p)
+		}
+	}
+	h.pinned = append(pinned, host)
+	h.setHosts(mergePinned(h.load().hosts, []Host{host}, h.key))
+
+	return nil
+}
+
+// Unpin removes the pinned entries with the given hostname, so that they are no longer merged after the reloads.
+// The entries stay in the host table until the next reload, which restores the ones of the config.
+func (h *staticHosts) Unpin(hostname string) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.pinned = removeHosts(h.pinned, hostname, h.key)
+	h.checksum = nil
+}
+
+// Pinned returns a copy of the pinned entries.
+func (h *staticHosts) Pinned() []Host {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	return cloneHosts(h.pinned)
+}
+
+// mergePinned returns a copy of hosts with the names of the pinned entries removed,
+// followed by the pinned entries.
+func mergePinned(hosts []Host, pinned []Host, key func(string) string) []Host {
+	for _, host := range pinned {
+		hosts = overrideHosts(hosts, host, key)
+	}
+	return append(hosts, pinned...)
+}
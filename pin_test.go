@@ -0,0 +1,94 @@
+package hosts
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestHostsPin(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	config := "192.168.1.1 example.com example\n192.168.1.2 example.org www.example.com"
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	var counts []int
+	hosts.OnReload(func(count int) {
+		counts = append(counts, count)
+	})
+
+	hosts.Pin(NewHost(net.IPv4(10, 0, 0, 1), "example.com", "www.example.com"))
+	hosts.Pin(NewHost(net.IPv4(10, 0, 0, 2), "pinned.example.com"))
+	hosts.Pin(NewHost(net.IPv4(10, 0, 0, 3), "pinned.example.com"))
+	if n := len(hosts.Pinned()); n != 2 {
+		t.Errorf("pinned entries should be 2, got %d", n)
+	}
+
+	var tests = []struct {
+		host string
+		ip   net.IP
+	}{
+		{"example.com", net.IPv4(10, 0, 0, 1)},
+		{"www.example.com", net.IPv4(10, 0, 0, 1)},
+		{"example", nil},
+		{"example.org", net.IPv4(192, 168, 1, 2)},
+		{"pinned.example.com", net.IPv4(10, 0, 0, 3)},
+	}
+	check := func(state string) {
+		for i, tc := range tests {
+			if ip := hosts.Lookup(tc.host); !ip.Equal(tc.ip) {
+				t.Errorf("#%d test failed: lookup of %s should be %s %s, got %s", i, tc.host, tc.ip, state, ip)
+			}
+		}
+	}
+	check("after pin")
+
+	if err := hosts.Reload(strings.NewReader(config + "\n192.168.1.3 pinned.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	check("after reload")
+	// the reload merged with the pinned entries leaves the table as it is after the pins
+	if len(counts) != 0 || hosts.Changed() || hosts.Count() != 3 {
+		t.Errorf("reload should not change the table of 3 entries, got callbacks %v and %d entries", counts, hosts.Count())
+	}
+
+	hosts.Unpin("example.com")
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Errorf("lookup should be %s until reload, got %s", net.IPv4(10, 0, 0, 1), ip)
+	}
+	if err := hosts.Reload(strings.NewReader(config + "\n192.168.1.3 pinned.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s after unpin, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+	if ip := hosts.Lookup("pinned.example.com"); !ip.Equal(net.IPv4(10, 0, 0, 3)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(10, 0, 0, 3), ip)
+	}
+}
+
+func TestHostsPinFamily(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("127.0.0.1 localhost")); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts.Pin(NewHost(net.IPv6loopback, "localhost"))
+	expected := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if ips := hosts.LookupAll("localhost"); !equalIPs(ips, expected) {
+		t.Errorf("lookup should be %v after pin, got %v", expected, ips)
+	}
+
+	hosts.Pin(NewHost(net.IPv4(127, 0, 0, 2), "localhost"))
+	if n := len(hosts.Pinned()); n != 2 {
+		t.Errorf("pinned entries should be 2, got %d", n)
+	}
+	if err := hosts.Reload(strings.NewReader("127.0.0.1 localhost")); err != nil {
+		t.Fatal(err)
+	}
+	expected = []net.IP{net.IPv6loopback, net.IPv4(127, 0, 0, 2)}
+	if ips := hosts.LookupAll("localhost"); !equalIPs(ips, expected) {
+		t.Errorf("lookup should be %v after reload, got %v", expected, ips)
+	}
+}
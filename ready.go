@@ -0,0 +1,24 @@
+package hosts
+
+// Ready returns a channel which is closed after the first successful reload,
+// which is useful to hold the traffic until the hosts are loaded.
+func (h *staticHosts) Ready() <-chan struct{} {
+	return h.ready
+}
+
+// IsReady checks whether the hosts are reloaded successfully at least once.
+func (h *staticHosts) IsReady() bool {
+	select {
+	case <-h.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// setReady marks the hosts as ready, it is safe to call it more than once.
+func (h *staticHosts) setReady() {
+	h.readyOnce.Do(func() {
+		close(h.ready)
+	})
+}
@@ -0,0 +1,145 @@
+package hosts
+
+import (
+	"io"
+	"time"
+)
+
+// Run starts a goroutine which reloads the hosts from the reader returned by source,
+// then reloads it again every reload period, until the reloader is stopped.
+// The period is re-read after each reload, so the reload option of the loaded config takes effect immediately.
+// A zero or negative period disables the automatic reloading, a failed reload is retried by the policy set by WithRetry.
+// If the reader returned by source is an io.Closer, it is closed after each reload.
+// The expired entries are removed from the host table while waiting for the next reload.
+// The source is kept to resume the reloading when the reloader is started again by Start.
+func (h *staticHosts) Run(source func() (io.Reader, error)) {
+	h.stopMux.Lock()
+	h.source = source
+	done := h.stopped
+	h.stopMux.Unlock()
+
+	h.spawn(func() { h.run(source, done) })
+}
+
+func (h *staticHosts) run(source func() (io.Reader, error), done <-chan struct{}) {
+	for {
+		if !h.reloadRetry(source, done) {
+			return
+		}
+
+		if !h.waitPeriod(done) {
+			return
+		}
+	}
+}
+
+// waitPeriod waits for the reload period from now like wait,
+// the period is re-read when it is changed by SetPeriod during the wait.
+func (h *staticHosts) waitPeriod(done <-chan struct{}) bool {
+	start := time.Now()
+	for {
+		var d time.Duration
+		if period := h.Period(); period > 0 {
+			if d = time.Until(start.Add(period)); d <= 0 {
+				return true
+			}
+		}
+		if !h.wait(d, done, h.periodChanged) {
+			return false
+		}
+	}
+}
+
+// RetryPolicy is the policy of retrying the failed reloads of Run, set by WithRetry.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts of a reload, including the first one.
+	Attempts int
+	// Delay is the delay before the first retry, it is doubled after each retry.
+	Delay time.Duration
+	// MaxDelay is the maximum delay between the retries, a zero MaxDelay means no maximum.
+	MaxDelay time.Duration
+}
+
+// reloadRetry reloads the hosts from source, and retries by the retry policy if the reload fails,
+// the host table is left as is until a reload succeeds.
+// It returns false if done is closed while waiting for a retry.
+func (h *staticHosts) reloadRetry(source func() (io.Reader, error), done <-chan struct{}) bool {
+	policy := h.options.retry
+	delay := policy.Delay
+	for attempt := 1; ; attempt++ {
+		if err := h.reloadSource(source); err == nil || attempt >= policy.Attempts {
+			return true
+		}
+
+		h.logf("hosts: reload: retry %d/%d in %v", attempt, policy.Attempts-1, delay)
+		if !h.wait(delay, done, nil) {
+			return false
+		}
+		if delay *= 2; policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+// OnReload registers f to be called with the number of entries after each successful reload.
+// The callbacks are called in the order of registration, without holding the lock of the host table,
+// so they are free to access the table.
+func (h *staticHosts) OnReload(f func(count int)) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.callbacks = append(h.callbacks[:len(h.callbacks):len(h.callbacks)], f)
+}
+
+// wait waits for d, or until done is closed if d is zero or negative,
+// meanwhile the expired entries are removed as they expire.
+// The wait ends early if wake receives a value.
+// It returns false if done is closed.
+func (h *staticHosts) wait(d time.Duration, done <-chan struct{}, wake <-chan struct{}) bool {
+	var deadline <-chan time.Time
+	if d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		var sweep <-chan time.Time
+		var timer *time.Timer
+		if t := h.nextExpires(); !t.IsZero() {
+			timer = time.NewTimer(time.Until(t))
+			sweep = timer.C
+		}
+
+		select {
+		case <-deadline:
+		case <-wake:
+		case <-sweep:
+			h.sweep()
+			continue
+		case <-done:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-done:
+			return false
+		default:
+			return true
+		}
+	}
+}
+
+func (h *staticHosts) reloadSource(source func() (io.Reader, error)) error {
+	r, err := source()
+	if err != nil {
+		h.reloadFailed(err)
+		return err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	return h.Reload(r)
+}
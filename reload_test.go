@@ -0,0 +1,462 @@
+package hosts
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostsRun(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	var n int32
+	hosts.Run(func() (io.Reader, error) {
+		i := atomic.AddInt32(&n, 1)
+		return strings.NewReader(fmt.Sprintf("reload 10ms\n192.168.1.%d example.com", i)), nil
+	})
+
+	for i := 0; i < 50 && atomic.LoadInt32(&n) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := atomic.LoadInt32(&n); v < 3 {
+		t.Errorf("source should be read periodically, got %d", v)
+	}
+	if ip := hosts.Lookup("example.com"); ip == nil {
+		t.Errorf("lookup should not be %v", ip)
+	}
+	if period := hosts.Period(); period != 10*time.Millisecond {
+		t.Errorf("period should be %s, got %s", 10*time.Millisecond, period)
+	}
+}
+
+func TestHostsRunNoPeriod(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	var n int32
+	hosts.Run(func() (io.Reader, error) {
+		atomic.AddInt32(&n, 1)
+		return strings.NewReader("192.168.1.1 example.com"), nil
+	})
+
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 1))
+	time.Sleep(50 * time.Millisecond)
+	if v := atomic.LoadInt32(&n); v != 1 {
+		t.Errorf("source should be read once, got %d", v)
+	}
+}
+
+func TestHostsOnReload(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+
+	var calls []string
+	hosts.OnReload(func(count int) {
+		calls = append(calls, fmt.Sprintf("a%d", count))
+		if ip := hosts.Lookup("example.com"); ip == nil {
+			t.Error("lookup should not be nil in callback")
+		}
+	})
+	hosts.OnReload(func(count int) {
+		calls = append(calls, fmt.Sprintf("b%d", count))
+	})
+
+	if err := hosts.Reload(strings.NewReader("example.com 192.168.1.1 192.168.1.2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := hosts.Reload(errReader{}); err == nil {
+		t.Error("reload should fail")
+	}
+	if err := hosts.Reload(strings.NewReader("192.168.1.1 example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, expected := strings.Join(calls, ","), "a2,b2,a1,b1"; s != expected {
+		t.Errorf("callbacks should be %s, got %s", expected, s)
+	}
+}
+
+func TestHostsReloadUnchanged(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+
+	var reloads int32
+	hosts.OnReload(func(count int) {
+		atomic.AddInt32(&reloads, 1)
+	})
+
+	config := "reload 10s\n192.168.1.1 example.com"
+	steps := []struct {
+		reload  func() error
+		reloads int32
+	}{
+		{func() error { return hosts.Reload(strings.NewReader(config)) }, 1},
+		{func() error { return hosts.Reload(strings.NewReader(config)) }, 1},
+		{func() error { return hosts.Reload(strings.NewReader(config + "\n# comment")) }, 1},
+		{func() error { return hosts.Reload(strings.NewReader(config + " example")) }, 2},
+		{func() error { return hosts.Reload(strings.NewReader(config)) }, 3},
+		{func() error { return hosts.Add(NewHost(net.IPv4(192, 168, 1, 2), "example.org")) }, 3},
+		{func() error { return hosts.Reload(strings.NewReader(config)) }, 4},
+		{func() error { return hosts.Reload(strings.NewReader("ttl 1h\n" + config)) }, 5},
+		{func() error { return hosts.Reload(strings.NewReader("ttl 1h\n" + config)) }, 6},
+	}
+	for i, step := range steps {
+		if err := step.reload(); err != nil {
+			t.Fatal(err)
+		}
+		if v := atomic.LoadInt32(&reloads); v != step.reloads {
+			t.Errorf("#%d test failed: reloads should be %d, got %d", i, step.reloads, v)
+		}
+	}
+	if ip := hosts.Lookup("example.org"); ip != nil {
+		t.Errorf("lookup should be nil, got %s", ip)
+	}
+}
+
+func TestHostsChanged(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if hosts.Changed() {
+		t.Error("changed should be false before reload")
+	}
+
+	steps := []struct {
+		config  string
+		changed bool
+	}{
+		{"192.168.1.1 example.com example", true},
+		{"192.168.1.1 example.com example", false},
+		{"  192.168.1.1\texample.com   example  ", false},
+		{"192.168.1.1 example.com example # comment", false},
+		{"192.168.1.1 example.com example\n192.168.1.1 example.com example", false},
+		{"192.168.1.1 EXAMPLE.com example", true},
+		{"192.168.1.1 example example.com", true},
+		{"192.168.1.1 example example.com\ncname example www.example", true},
+	}
+	for i, step := range steps {
+		if err := hosts.Reload(strings.NewReader(step.config)); err != nil {
+			t.Fatal(err)
+		}
+		if changed := hosts.Changed(); changed != step.changed {
+			t.Errorf("#%d test failed: changed should be %v, got %v", i, step.changed, changed)
+		}
+	}
+
+	if err := hosts.Reload(errReader{}); err == nil {
+		t.Error("reload should fail")
+	}
+	if hosts.Changed() {
+		t.Error("changed should be false after a failed reload")
+	}
+}
+
+func TestHostsReloadConcurrent(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+
+	var reloads int32
+	hosts.OnReload(func(count int) {
+		atomic.AddInt32(&reloads, 1)
+		if count != 2 {
+			t.Errorf("count should be %d, got %d", 2, count)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			r := fmt.Sprintf("reload %ds\nexample.com 192.168.1.%d\n192.168.2.%d example.org", i+1, i, i)
+			if err := hosts.Reload(strings.NewReader(r)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			hosts.Lookup("example.com")
+			hosts.ReverseLookup(net.IPv4(192, 168, 1, 1))
+		}()
+		go func() {
+			defer wg.Done()
+			hosts.Period()
+			_ = hosts.String()
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&reloads); n != 50 {
+		t.Errorf("reloads should be %d, got %d", 50, n)
+	}
+	if n := hosts.Count(); n != 2 {
+		t.Errorf("count should be %d, got %d", 2, n)
+	}
+	ip := hosts.Lookup("example.com")
+	if ip == nil {
+		t.Fatal("lookup should not be nil")
+	}
+	if period, expected := hosts.Period(), time.Duration(ip.To4()[3]+1)*time.Second; period != expected {
+		t.Errorf("period should be %s from the same reload as %s, got %s", expected, ip, period)
+	}
+}
+
+func TestHostsStopStart(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	var n int32
+	hosts.Run(func() (io.Reader, error) {
+		i := atomic.AddInt32(&n, 1)
+		return strings.NewReader(fmt.Sprintf("reload 10ms\n192.168.1.%d example.com", i)), nil
+	})
+
+	for cycle := 0; cycle < 3; cycle++ {
+		start := atomic.LoadInt32(&n)
+		for i := 0; i < 50 && atomic.LoadInt32(&n) < start+2; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if v := atomic.LoadInt32(&n); v < start+2 {
+			t.Fatalf("#%d test failed: source should be read periodically, got %d reads", cycle, v-start)
+		}
+
+		hosts.Stop()
+		if !hosts.Stopped() || hosts.Period() != -1 {
+			t.Errorf("#%d test failed: reloader should be stopped, got period %s", cycle, hosts.Period())
+		}
+		// a reload in progress may complete after Stop.
+		time.Sleep(20 * time.Millisecond)
+		stopped := atomic.LoadInt32(&n)
+		time.Sleep(50 * time.Millisecond)
+		if v := atomic.LoadInt32(&n); v != stopped {
+			t.Errorf("#%d test failed: source should not be read when stopped, got %d reads", cycle, v-stopped)
+		}
+		if err := hosts.Reload(strings.NewReader("192.168.2.1 example.org")); err != nil {
+			t.Fatal(err)
+		}
+		if ip := hosts.Lookup("example.org"); ip != nil {
+			t.Errorf("#%d test failed: lookup should be nil when stopped, got %s", cycle, ip)
+		}
+
+		hosts.Start()
+		hosts.Start() // no-op on a running reloader
+		if hosts.Stopped() {
+			t.Errorf("#%d test failed: reloader should be started", cycle)
+		}
+	}
+}
+
+func TestHostsRestart(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	var n int32
+	hosts.Run(func() (io.Reader, error) {
+		atomic.AddInt32(&n, 1)
+		return strings.NewReader("192.168.1.1 example.com"), nil
+	})
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 1))
+
+	hosts.Restart()
+	for i := 0; i < 50 && atomic.LoadInt32(&n) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := atomic.LoadInt32(&n); v != 2 {
+		t.Errorf("source should be read again on restart, got %d reads", v)
+	}
+	if hosts.Stopped() {
+		t.Error("reloader should be started")
+	}
+}
+
+func TestHostsReady(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))).(*staticHosts)
+	if hosts.IsReady() {
+		t.Error("hosts should not be ready before a reload")
+	}
+
+	if err := hosts.Reload(errReader{}); err == nil {
+		t.Error("reload should fail")
+	}
+	if hosts.IsReady() {
+		t.Error("hosts should not be ready after a failed reload")
+	}
+
+	go hosts.Reload(strings.NewReader("192.168.1.2 example.com"))
+	select {
+	case <-hosts.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("hosts should be ready after a reload")
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 2)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 2), ip)
+	}
+
+	if err := hosts.Reload(strings.NewReader("192.168.1.3 example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if !hosts.IsReady() {
+		t.Error("hosts should stay ready")
+	}
+}
+
+func TestHostsRunRetry(t *testing.T) {
+	hosts := NewHosts(WithRetry(RetryPolicy{Attempts: 3, Delay: 10 * time.Millisecond})).(*staticHosts)
+	defer hosts.Stop()
+
+	var mux sync.Mutex
+	var calls []time.Time
+	hosts.Run(func() (io.Reader, error) {
+		mux.Lock()
+		defer mux.Unlock()
+		calls = append(calls, time.Now())
+		if len(calls) < 3 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return strings.NewReader("192.168.1.1 example.com"), nil
+	})
+
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 1))
+	mux.Lock()
+	defer mux.Unlock()
+	if len(calls) != 3 {
+		t.Fatalf("source should be read 3 times, got %d", len(calls))
+	}
+	if d := calls[1].Sub(calls[0]); d < 10*time.Millisecond {
+		t.Errorf("first retry should be delayed by 10ms, got %v", d)
+	}
+	if d := calls[2].Sub(calls[1]); d < 20*time.Millisecond {
+		t.Errorf("second retry should be delayed by 20ms, got %v", d)
+	}
+}
+
+func TestHostsRunRetryGiveUp(t *testing.T) {
+	hosts := NewHosts(WithRetry(RetryPolicy{Attempts: 2, Delay: time.Millisecond})).(*staticHosts)
+	defer hosts.Stop()
+
+	var n int32
+	hosts.Run(func() (io.Reader, error) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			return strings.NewReader("reload 20ms\n192.168.1.1 example.com"), nil
+		}
+		return nil, io.ErrUnexpectedEOF
+	})
+
+	for i := 0; i < 100 && atomic.LoadInt32(&n) < 5; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if v := atomic.LoadInt32(&n); v < 5 {
+		t.Errorf("source should be read again in the next periods, got %d", v)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+}
+
+func TestHostsSetPeriod(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	// the config changes on each read, so that each reload changes the table.
+	var n int32
+	hosts.Run(func() (io.Reader, error) {
+		v := atomic.AddInt32(&n, 1)
+		return strings.NewReader(fmt.Sprintf("192.168.1.1 example.com\n192.168.2.%d example.org", v)), nil
+	})
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 1))
+
+	hosts.SetPeriod(10 * time.Millisecond)
+	if period := hosts.Period(); period != 10*time.Millisecond {
+		t.Errorf("period should be %s, got %s", 10*time.Millisecond, period)
+	}
+	for i := 0; i < 50 && atomic.LoadInt32(&n) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := atomic.LoadInt32(&n); v < 3 {
+		t.Errorf("source should be read periodically, got %d", v)
+	}
+	if period := hosts.Period(); period != 10*time.Millisecond {
+		t.Errorf("period should be kept by a config without the reload option, got %s", period)
+	}
+
+	hosts.SetPeriod(0)
+	time.Sleep(20 * time.Millisecond)
+	v := atomic.LoadInt32(&n)
+	time.Sleep(50 * time.Millisecond)
+	if v2 := atomic.LoadInt32(&n); v2 != v {
+		t.Errorf("source should not be read after disabling the period, got %d more reads", v2-v)
+	}
+}
+
+func TestHostsReloadPeriodRemoved(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	var tests = []struct {
+		config string
+		set    time.Duration // the period set by SetPeriod before the reload, if any
+		period time.Duration
+	}{
+		{"reload 30s\n192.168.1.1 example.com", 0, 30 * time.Second},
+		{"192.168.1.1 example.com", 0, 0},
+		{"192.168.1.2 example.com", 10 * time.Second, 10 * time.Second},
+		{"reload 30s\n192.168.1.1 example.com", 10 * time.Second, 30 * time.Second},
+		{"192.168.1.3 example.com", 0, 0},
+	}
+	for i, tc := range tests {
+		if tc.set > 0 {
+			hosts.SetPeriod(tc.set)
+		}
+		if err := hosts.Reload(strings.NewReader(tc.config)); err != nil {
+			t.Fatalf("#%d test failed: %v", i, err)
+		}
+		if period := hosts.Period(); period != tc.period {
+			t.Errorf("#%d test failed: period should be %s, got %s", i, tc.period, period)
+		}
+	}
+}
+
+func TestHostsWait(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var calls int32
+	hosts.Run(func() (io.Reader, error) {
+		atomic.AddInt32(&calls, 1)
+		entered <- struct{}{}
+		<-release
+		return strings.NewReader("reload 10ms\n192.168.1.1 example.com"), nil
+	})
+	<-entered
+	hosts.Stop()
+
+	waited := make(chan struct{})
+	go func() {
+		hosts.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("wait should block until the reload in progress is done")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("wait should return after the goroutine exits")
+	}
+	if ip := hosts.Lookup("example.com"); ip != nil {
+		t.Errorf("reload after stop should be dropped, got %s", ip)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("source should be read once, got %d", n)
+	}
+
+	// the goroutines of a stopped reloader are not running.
+	hosts.Wait()
+}
@@ -0,0 +1,30 @@
+package hosts
+
+import (
+	"io"
+	"os"
+	"os/signal"
+)
+
+// ReloadOnSignal starts a goroutine which reloads the hosts from the reader returned by source
+// each time sig is received, until the reloader is stopped.
+// The errors of the reloads, including the errors returned by source, are reported to the logger.
+// If the reader returned by source is an io.Closer, it is closed after each reload.
+func (h *staticHosts) ReloadOnSignal(sig os.Signal, source func() (io.Reader, error)) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sig)
+	done := h.done()
+
+	h.spawn(func() {
+		defer signal.Stop(c)
+
+		for {
+			select {
+			case <-c:
+				h.reloadSource(source)
+			case <-done:
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+// +build !windows
+
+package hosts
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHostsReloadOnSignal(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	var n int32
+	hosts.ReloadOnSignal(syscall.SIGHUP, func() (io.Reader, error) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			return nil, errors.New("source error")
+		}
+		return strings.NewReader("192.168.1.1 example.com"), nil
+	})
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && atomic.LoadInt32(&n) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 1))
+}
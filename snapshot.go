@@ -0,0 +1,60 @@
+package hosts
+
+import (
+	"time"
+)
+
+// Snapshot is an immutable copy of the state of the host table, taken by Snapshot.
+type Snapshot struct {
+	hosts   []Host
+	nets    []netHost
+	cnames  map[string]cname
+	negated map[string]string
+	period  time.Duration
+}
+
+// Snapshot returns a deep copy of the entries, the networks, the cname aliases, the negated names and the reload period of the host table,
+// which can be passed to Restore to roll back the later changes.
+func (h *staticHosts) Snapshot() Snapshot {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	t := h.load()
+	return Snapshot{
+		hosts:   cloneHosts(t.hosts),
+		nets:    cloneNets(t.nets),
+		cnames:  t.cnames,
+		negated: t.negated,
+		period:  h.period,
+	}
+}
+
+// Restore atomically replaces the entries, the networks, the cname aliases, the negated names and the reload period of the host table with s.
+// The entries keep their original expiration times, and s can be restored more than once.
+// Restore is serialized with the reloads.
+func (h *staticHosts) Restore(s Snapshot) {
+	hosts := cloneHosts(s.hosts)
+	nets := cloneNets(s.nets)
+
+	h.reloadMux.Lock()
+	defer h.reloadMux.Unlock()
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.period = s.period
+	t := (&table{nets: nets, cnames: s.cnames, negated: s.negated}).withHosts(hosts, h.key)
+	h.setTable(t)
+}
+
+// cloneHosts returns a deep copy of hosts.
+func cloneHosts(hosts []Host) []Host {
+	if hosts == nil {
+		return nil
+	}
+	v := make([]Host, 0, len(hosts))
+	for _, host := range hosts {
+		v = append(v, host.clone())
+	}
+	return v
+}
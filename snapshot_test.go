@@ -0,0 +1,54 @@
+package hosts
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostsSnapshotRestore(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("reload 10s\n192.168.1.1 example.com example")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := hosts.Snapshot()
+
+	// the snapshot must not share memory with the host table.
+	hosts.mux.Lock()
+	ip := hosts.load().hosts[0].IP
+	ip[len(ip)-1] = 2
+	hosts.load().hosts[0].Aliases[0] = "changed"
+	hosts.mux.Unlock()
+
+	if err := hosts.Reload(strings.NewReader("192.168.1.3 example.org")); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); ip != nil {
+		t.Errorf("lookup should be nil, got %s", ip)
+	}
+
+	for i := 0; i < 2; i++ {
+		hosts.Restore(s)
+		if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+			t.Errorf("#%d test failed: lookup should be %s, got %s", i, net.IPv4(192, 168, 1, 1), ip)
+		}
+		if ip := hosts.Lookup("example"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+			t.Errorf("#%d test failed: lookup should be %s, got %s", i, net.IPv4(192, 168, 1, 1), ip)
+		}
+		if ip := hosts.Lookup("example.org"); ip != nil {
+			t.Errorf("#%d test failed: lookup should be nil, got %s", i, ip)
+		}
+		if period := hosts.Period(); period != 10*time.Second {
+			t.Errorf("#%d test failed: period should be %s, got %s", i, 10*time.Second, period)
+		}
+
+		// modifying the restored table must not affect the snapshot.
+		hosts.mux.Lock()
+		ip := hosts.load().hosts[0].IP
+		ip[len(ip)-1] = 2
+		hosts.mux.Unlock()
+		hosts.Clear()
+	}
+}
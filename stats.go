@@ -0,0 +1,150 @@
+package hosts
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is the statistics of the lookups of Hosts.
+type Stats struct {
+	Lookups      uint64 // total number of lookups
+	Hits         uint64 // number of lookups with the host found
+	Misses       uint64 // number of lookups with the host not found
+	Reloads      uint64 // number of successful reloads
+	ReloadErrors uint64 // number of failed reloads, including the failures to read the sources
+	// LookupTime is the distribution of the durations of the lookups, they are only timed with WithLookupTiming.
+	LookupTime LookupTime
+}
+
+// LookupTimeBuckets is the upper bounds of the buckets of LookupTime.
+var LookupTimeBuckets = [...]time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+}
+
+// LookupTime is the distribution of the durations of the timed lookups.
+type LookupTime struct {
+	Count uint64 // number of timed lookups
+	Total time.Duration
+	Min   time.Duration
+	Max   time.Duration
+	// Buckets is the numbers of the lookups by duration, Buckets[i] counts the lookups longer than LookupTimeBuckets[i-1]
+	// and not longer than LookupTimeBuckets[i], the last one counts the lookups longer than all the bounds.
+	Buckets [len(LookupTimeBuckets) + 1]uint64
+}
+
+// Avg returns the average duration of the lookups, zero if no lookup is timed.
+func (t LookupTime) Avg() time.Duration {
+	if t.Count == 0 {
+		return 0
+	}
+	return t.Total / time.Duration(t.Count)
+}
+
+// counters is the atomic counters of Stats.
+// It must be the first field of its enclosing struct for the 64-bit alignment on 32-bit platforms.
+type counters struct {
+	lookups      uint64
+	hits         uint64
+	misses       uint64
+	reloads      uint64
+	reloadErrors uint64
+	timeCount    uint64
+	timeTotal    uint64 // in nanoseconds
+	timeMin      uint64 // the minimum in nanoseconds plus one, zero if no lookup is timed
+	timeMax      uint64 // in nanoseconds
+	timeBuckets  [len(LookupTimeBuckets) + 1]uint64
+}
+
+// lookup counts a lookup, and records its duration if start is not zero.
+func (c *counters) lookup(hit bool, start time.Time) {
+	atomic.AddUint64(&c.lookups, 1)
+	if hit {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+	if !start.IsZero() {
+		c.time(time.Since(start))
+	}
+}
+
+// time records the duration d of a lookup.
+func (c *counters) time(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	n := uint64(d)
+	atomic.AddUint64(&c.timeCount, 1)
+	atomic.AddUint64(&c.timeTotal, n)
+	for {
+		v := atomic.LoadUint64(&c.timeMin)
+		if v != 0 && v <= n+1 || atomic.CompareAndSwapUint64(&c.timeMin, v, n+1) {
+			break
+		}
+	}
+	for {
+		v := atomic.LoadUint64(&c.timeMax)
+		if v >= n || atomic.CompareAndSwapUint64(&c.timeMax, v, n) {
+			break
+		}
+	}
+
+	i := 0
+	for i < len(LookupTimeBuckets) && d > LookupTimeBuckets[i] {
+		i++
+	}
+	atomic.AddUint64(&c.timeBuckets[i], 1)
+}
+
+// startLookup returns the start time of a lookup if the lookups are timed, or the zero time.
+func (h *staticHosts) startLookup() time.Time {
+	if !h.options.lookupTiming {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// FamilyCounts returns the numbers of the entries with an IPv4 address and with an IPv6 address,
+// which are counted when the index of the host table is built.
+// The IPv4-mapped IPv6 addresses are counted as IPv4 addresses.
+func (h *staticHosts) FamilyCounts() (v4, v6 int) {
+	idx := h.load().index
+	return idx.v4, idx.v6
+}
+
+// Stats returns the statistics of the lookups.
+func (h *staticHosts) Stats() Stats {
+	return Stats{
+		Lookups:      atomic.LoadUint64(&h.counters.lookups),
+		Hits:         atomic.LoadUint64(&h.counters.hits),
+		Misses:       atomic.LoadUint64(&h.counters.misses),
+		Reloads:      atomic.LoadUint64(&h.counters.reloads),
+		ReloadErrors: atomic.LoadUint64(&h.counters.reloadErrors),
+		LookupTime:   h.counters.lookupTime(),
+	}
+}
+
+func (c *counters) lookupTime() LookupTime {
+	t := LookupTime{
+		Count: atomic.LoadUint64(&c.timeCount),
+		Total: time.Duration(atomic.LoadUint64(&c.timeTotal)),
+		Max:   time.Duration(atomic.LoadUint64(&c.timeMax)),
+	}
+	if v := atomic.LoadUint64(&c.timeMin); v > 0 {
+		t.Min = time.Duration(v - 1)
+	}
+	for i := range t.Buckets {
+		t.Buckets[i] = atomic.LoadUint64(&c.timeBuckets[i])
+	}
+	return t
+}
+
+// reloadFailed logs the error of a failed reload, and counts it.
+func (h *staticHosts) reloadFailed(err error) {
+	atomic.AddUint64(&h.counters.reloadErrors, 1)
+	h.logf("hosts: reload: %v", err)
+}
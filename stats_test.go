@@ -0,0 +1,96 @@
+package hosts
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostsStats(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))).(*staticHosts)
+
+	hosts.Lookup("example.com")
+	hosts.LookupAll("example.com")
+	hosts.LookupAll("example.org")
+	hosts.LookupRoundRobin("example.com")
+	hosts.Lookup("")
+
+	expected := Stats{Lookups: 4, Hits: 3, Misses: 1}
+	if stats := hosts.Stats(); stats != expected {
+		t.Errorf("stats should be %+v, got %+v", expected, stats)
+	}
+}
+
+func TestHostsFamilyCounts(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if v4, v6 := hosts.FamilyCounts(); v4 != 0 || v6 != 0 {
+		t.Errorf("counts should be 0 and 0, got %d and %d", v4, v6)
+	}
+
+	config := "192.168.1.1 ::1 ::ffff:192.168.1.2 example.com\n2001:db8::1 example.org\n10.0.0.0/8 corp\n192.168.1.1 example.com"
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if v4, v6 := hosts.FamilyCounts(); v4 != 2 || v6 != 2 {
+		t.Errorf("counts should be 2 and 2, got %d and %d", v4, v6)
+	}
+
+	hosts.Remove("example.org")
+	if v4, v6 := hosts.FamilyCounts(); v4 != 2 || v6 != 1 {
+		t.Errorf("counts should be 2 and 1 after remove, got %d and %d", v4, v6)
+	}
+}
+
+func TestHostsStatsReloads(t *testing.T) {
+	hosts := NewHosts(WithLogger(nopLogger{})).(*staticHosts)
+
+	if err := hosts.Reload(strings.NewReader("192.168.1.1 example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := hosts.Reload(errReader{}); err == nil {
+		t.Fatal("reload should fail")
+	}
+	if err := hosts.Reload(strings.NewReader("192.168.1.1 example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Stats{Reloads: 2, ReloadErrors: 1}
+	if stats := hosts.Stats(); stats != expected {
+		t.Errorf("stats should be %+v, got %+v", expected, stats)
+	}
+}
+
+func TestHostsLookupTiming(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))).(*staticHosts)
+	hosts.Lookup("example.com")
+	if lt := hosts.Stats().LookupTime; lt != (LookupTime{}) {
+		t.Errorf("lookups should not be timed by default, got %+v", lt)
+	}
+
+	hosts = NewHosts(
+		WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com")),
+		WithLookupTiming(true),
+	).(*staticHosts)
+	hosts.Lookup("example.com")
+	hosts.LookupIPAddr("example.org")
+	lt := hosts.Stats().LookupTime
+	if lt.Count != 2 || lt.Min > lt.Max || lt.Total < lt.Max || lt.Avg() != lt.Total/2 {
+		t.Errorf("lookup time should record 2 lookups, got %+v", lt)
+	}
+
+	var c counters
+	for _, d := range []time.Duration{500 * time.Nanosecond, time.Microsecond, 50 * time.Microsecond, 5 * time.Millisecond, time.Second} {
+		c.time(d)
+	}
+	expected := LookupTime{
+		Count:   5,
+		Total:   time.Second + 5*time.Millisecond + 50*time.Microsecond + 1500*time.Nanosecond,
+		Min:     500 * time.Nanosecond,
+		Max:     time.Second,
+		Buckets: [len(LookupTimeBuckets) + 1]uint64{2, 0, 1, 0, 1, 1},
+	}
+	if lt := c.lookupTime(); lt != expected {
+		t.Errorf("lookup time should be %+v, got %+v", expected, lt)
+	}
+}
@@ -0,0 +1,179 @@
+package hosts
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// subscription is a subscriber of the changes of the IP address of a host.
+type subscription struct {
+	host string
+	last net.IP
+	ch   chan net.IP
+}
+
+// allSubscription is a subscriber of the changes of all the IP addresses of a host.
+type allSubscription struct {
+	host string
+	last []net.IP
+	ch   chan []net.IP
+}
+
+// subscriptions is the subscribers of the changes of the hosts.
+type subscriptions struct {
+	subs []*subscription
+	all  []*allSubscription // kept across Stop and Start
+	mux  sync.Mutex
+}
+
+// Subscribe returns a channel which receives the new IP address of host each time a reload changes it,
+// the address is nil if host is removed. The address is the one returned by Lookup.
+// The channel is buffered, if the subscriber falls behind only the latest address is kept.
+// The channel is closed when the reloader is stopped.
+func (h *staticHosts) Subscribe(host string) <-chan net.IP {
+	sub := &subscription{
+		host: host,
+		last: h.current(host),
+		ch:   make(chan net.IP, 1),
+	}
+
+	h.subs.mux.Lock()
+	defer h.subs.mux.Unlock()
+
+	// Stop closes the channels after the reloader is stopped, so a subscription is never left open.
+	if h.Stopped() {
+		close(sub.ch)
+		return sub.ch
+	}
+	h.subs.subs = append(h.subs.subs, sub)
+	return sub.ch
+}
+
+// Unsubscribe cancels the subscription of the channel ch returned by Subscribe, ch is closed and receives no more addresses.
+// It does nothing if ch is not subscribed, such as after the reloader is stopped.
+func (h *staticHosts) Unsubscribe(ch <-chan net.IP) {
+	h.subs.mux.Lock()
+	defer h.subs.mux.Unlock()
+
+	for i, sub := range h.subs.subs {
+		if sub.ch == ch {
+			close(sub.ch)
+			h.subs.subs = append(h.subs.subs[:i], h.subs.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// SubscribeAll is like Subscribe, but the channel receives all the addresses of host, like LookupAll,
+// each time a reload changes any of them, such as adding or removing one of the addresses of a host.
+// The addresses are nil if host is removed.
+// Unlike the one of Subscribe, the channel is kept open when the reloader is stopped, and receives the changes again after Start,
+// it is only closed by UnsubscribeAll.
+func (h *staticHosts) SubscribeAll(host string) <-chan []net.IP {
+	sub := &allSubscription{
+		host: host,
+		last: h.currentAll(host),
+		ch:   make(chan []net.IP, 1),
+	}
+
+	h.subs.mux.Lock()
+	defer h.subs.mux.Unlock()
+
+	h.subs.all = append(h.subs.all, sub)
+	return sub.ch
+}
+
+// UnsubscribeAll cancels the subscription of the channel ch returned by SubscribeAll, ch is closed and receives no more addresses.
+// It does nothing if ch is not subscribed.
+func (h *staticHosts) UnsubscribeAll(ch <-chan []net.IP) {
+	h.subs.mux.Lock()
+	defer h.subs.mux.Unlock()
+
+	for i, sub := range h.subs.all {
+		if sub.ch == ch {
+			close(sub.ch)
+			h.subs.all = append(h.subs.all[:i], h.subs.all[i+1:]...)
+			return
+		}
+	}
+}
+
+// publish sends the changed addresses to the subscribers.
+func (h *staticHosts) publish() {
+	h.subs.mux.Lock()
+	defer h.subs.mux.Unlock()
+
+	for _, sub := range h.subs.all {
+		ips := h.currentAll(sub.host)
+		if sameIPs(ips, sub.last) {
+			continue
+		}
+		sub.last = ips
+
+		select {
+		case <-sub.ch: // drop the stale addresses
+		default:
+		}
+		sub.ch <- ips
+	}
+
+	for _, sub := range h.subs.subs {
+		ip := h.current(sub.host)
+		if ip.Equal(sub.last) {
+			continue
+		}
+		sub.last = ip
+
+		select {
+		case <-sub.ch: // drop the stale address
+		default:
+		}
+		sub.ch <- ip
+	}
+}
+
+// close closes the channels of the subscribers of Subscribe.
+func (s *subscriptions) close() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, sub := range s.subs {
+		close(sub.ch)
+	}
+	s.subs = nil
+}
+
+// current returns the address of host like Lookup, without counting the lookup.
+func (h *staticHosts) current(host string) net.IP {
+	if v, ok := h.lookupHost(h.load(), host); ok {
+		return copyIP(v.IP)
+	}
+	return nil
+}
+
+// currentAll returns the addresses of host like LookupAll, without counting the lookup.
+func (h *staticHosts) currentAll(host string) []net.IP {
+	t := h.load()
+	var ips []net.IP
+	var now time.Time
+	for _, i := range h.lookupIndexes(t, host) {
+		if !t.hosts[i].expired(&now) {
+			ips = appendIP(ips, t.hosts[i].IP)
+		}
+	}
+	return ips
+}
+
+// sameIPs checks whether a and b have the same addresses in the same order.
+func sameIPs(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
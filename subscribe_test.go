@@ -0,0 +1,152 @@
+package hosts
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostsSubscribe(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))).(*staticHosts)
+
+	ch := hosts.Subscribe("example.com")
+	other := hosts.Subscribe("example.org")
+
+	reloads := []struct {
+		config string
+		ip     net.IP
+		sent   bool
+	}{
+		{"192.168.1.1 example.com", nil, false},
+		{"192.168.1.2 example.com", net.IPv4(192, 168, 1, 2), true},
+		{"192.168.1.2 example.com\n192.168.1.3 example.com", nil, false},
+		{"192.168.1.3 example.net", nil, true},
+		{"192.168.1.4 example.com", net.IPv4(192, 168, 1, 4), true},
+	}
+	for i, tc := range reloads {
+		if err := hosts.Reload(strings.NewReader(tc.config)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case ip := <-ch:
+			if !tc.sent || !ip.Equal(tc.ip) {
+				t.Errorf("#%d test failed: address should be %s (sent %t), got %s", i, tc.ip, tc.sent, ip)
+			}
+		default:
+			if tc.sent {
+				t.Errorf("#%d test failed: address %s should be sent", i, tc.ip)
+			}
+		}
+	}
+
+	select {
+	case ip := <-other:
+		t.Errorf("address of example.org should not be sent, got %s", ip)
+	default:
+	}
+
+	hosts.Stop()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("channel should be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel should be closed")
+	}
+	if _, ok := <-hosts.Subscribe("example.com"); ok {
+		t.Error("channel should be closed when subscribed after stop")
+	}
+}
+
+func TestHostsSubscribeLatest(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	ch := hosts.Subscribe("example.com")
+	for i := 1; i <= 3; i++ {
+		if err := hosts.Reload(strings.NewReader(fmt.Sprintf("192.168.1.%d example.com", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ip := <-ch; !ip.Equal(net.IPv4(192, 168, 1, 3)) {
+		t.Errorf("address should be %s, got %s", net.IPv4(192, 168, 1, 3), ip)
+	}
+}
+
+func TestHostsUnsubscribe(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	ch := hosts.Subscribe("example.com")
+	other := hosts.Subscribe("example.com")
+	hosts.Unsubscribe(ch)
+	hosts.Unsubscribe(ch)
+	if _, ok := <-ch; ok {
+		t.Error("channel should be closed")
+	}
+
+	if err := hosts.Reload(strings.NewReader("192.168.1.1 example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if ip := <-other; !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("address should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+	if n := len(hosts.subs.subs); n != 1 {
+		t.Errorf("subscriptions should be 1, got %d", n)
+	}
+}
+
+func TestHostsSubscribeAll(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com"))).(*staticHosts)
+	defer hosts.Stop()
+
+	ch := hosts.SubscribeAll("example.com")
+	reloads := []struct {
+		config string
+		ips    []net.IP
+		sent   bool
+	}{
+		{"192.168.1.1 example.com", nil, false},
+		{"192.168.1.1 example.com\n192.168.1.2 example.com", []net.IP{net.IPv4(192, 168, 1, 1), net.IPv4(192, 168, 1, 2)}, true},
+		{"example.com 192.168.1.1 192.168.1.2", nil, false},
+		{"192.168.1.1 example.com", []net.IP{net.IPv4(192, 168, 1, 1)}, true},
+		{"192.168.1.3 example.net", nil, true},
+	}
+	for i, tc := range reloads {
+		if err := hosts.Reload(strings.NewReader(tc.config)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case ips := <-ch:
+			if !tc.sent || !equalIPs(ips, tc.ips) {
+				t.Errorf("#%d test failed: addresses should be %v (sent %t), got %v", i, tc.ips, tc.sent, ips)
+			}
+		default:
+			if tc.sent {
+				t.Errorf("#%d test failed: addresses %v should be sent", i, tc.ips)
+			}
+		}
+	}
+
+	// the subscription is kept across a restart.
+	hosts.Restart()
+	if err := hosts.Reload(strings.NewReader("192.168.1.4 example.com")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ips, ok := <-ch:
+		if !ok || !equalIPs(ips, []net.IP{net.IPv4(192, 168, 1, 4)}) {
+			t.Errorf("addresses should be %s after restart, got %v (open %t)", net.IPv4(192, 168, 1, 4), ips, ok)
+		}
+	case <-time.After(time.Second):
+		t.Error("addresses should be sent after restart")
+	}
+
+	hosts.UnsubscribeAll(ch)
+	if _, ok := <-ch; ok {
+		t.Error("channel should be closed")
+	}
+}
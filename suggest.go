@@ -0,0 +1,88 @@
+package hosts
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Suggest returns up to max hostnames and aliases of the host table nearest to host,
+// by the Levenshtein distance of their normalized forms, which can be hints for typos on a lookup miss.
+// The nearest names come first, names with the same distance are in the order they appear.
+// Wildcards and patterns are not suggested.
+// Suggest scans the whole host table, it is meant for diagnostics rather than for the lookups.
+func (h *staticHosts) Suggest(host string, max int) []string {
+	if h == nil || host == "" || max <= 0 {
+		return nil
+	}
+	key := h.key(trimPort(host))
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	seen := make(map[string]bool)
+
+	var now time.Time
+	for _, v := range h.load().hosts {
+		if v.expired(&now) {
+			continue
+		}
+		for _, name := range append([]string{v.Hostname}, v.Aliases...) {
+			if name == "" || strings.HasPrefix(name, "*.") || isPattern(name) {
+				continue
+			}
+			k := h.key(name)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			candidates = append(candidates, candidate{name: name, distance: levenshtein(key, k)})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	if len(candidates) > max {
+		candidates = candidates[:max]
+	}
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// levenshtein returns the Levenshtein distance between a and b, in runes.
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	row := make([]int, len(t)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			v := min3(row[j]+1, row[j-1]+1, prev+cost)
+			prev, row[j] = row[j], v
+		}
+	}
+	return row[len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
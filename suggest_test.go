@@ -0,0 +1,57 @@
+package hosts
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+var levenshteinTests = []struct {
+	a, b     string
+	distance int
+}{
+	{"", "", 0},
+	{"", "abc", 3},
+	{"abc", "", 3},
+	{"example.com", "example.com", 0},
+	{"exmaple.com", "example.com", 2},
+	{"example.con", "example.com", 1},
+	{"kitten", "sitting", 3},
+	{"münchen", "munchen", 1},
+}
+
+func TestLevenshtein(t *testing.T) {
+	for i, tc := range levenshteinTests {
+		if d := levenshtein(tc.a, tc.b); d != tc.distance {
+			t.Errorf("#%d test failed: distance should be %d, got %d", i, tc.distance, d)
+		}
+	}
+}
+
+var hostsSuggestTests = []struct {
+	host  string
+	max   int
+	names []string
+}{
+	{"example.con", 0, nil},
+	{"", 3, nil},
+	{"example.con", 1, []string{"example.com"}},
+	{"EXAMPLE.con", 2, []string{"example.com", "example.org"}},
+	{"exampel", 3, []string{"example", "example.com", "example.org"}},
+	{"foo", 10, []string{"example", "example.com", "example.org"}},
+}
+
+func TestHostsSuggest(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"),
+		NewHost(net.IPv4(192, 168, 1, 2), "example.com"),
+		NewHost(net.IPv4(192, 168, 1, 3), "example.org", "*.example.org", `re:ads\d`),
+	)).(*staticHosts)
+
+	for i, tc := range hostsSuggestTests {
+		names := hosts.Suggest(tc.host, tc.max)
+		if strings.Join(names, ",") != strings.Join(tc.names, ",") {
+			t.Errorf("#%d test failed: suggestions should be %v, got %v", i, tc.names, names)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package hosts
+
+// NewHostsFromSystem creates a Hosts with the options, loaded from the hosts file of the operating system.
+func NewHostsFromSystem(opts ...Option) (Hosts, error) {
+	h := NewHosts(opts...).(*staticHosts)
+	if err := h.ReloadFile(systemHostsFile()); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
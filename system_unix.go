@@ -0,0 +1,8 @@
+//go:build !windows
+// +build !windows
+
+package hosts
+
+func systemHostsFile() string {
+	return "/etc/hosts"
+}
@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package hosts
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func systemHostsFile() string {
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = `C:\Windows`
+	}
+	return filepath.Join(root, "System32", "drivers", "etc", "hosts")
+}
@@ -0,0 +1,106 @@
+package hosts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LookupByTag returns a copy of the entries of the host table with the tag key of value, in order.
+// The entries are the ones of the table when LookupByTag is called.
+func (h *staticHosts) LookupByTag(key, value string) []Host {
+	return h.Filter(func(host Host) bool {
+		v, ok := host.Tags[key]
+		return ok && v == value
+	})
+}
+
+// splitTags splits the trailing tags of line, the fields of the form ckey=value separated by white space,
+// where c is the comment character, e.g. "#category=ads". The last tag of a key wins.
+// A comment may precede the tags, which are not part of the comment.
+func splitTags(line string, c byte) (string, map[string]string) {
+	var tags map[string]string
+	for {
+		s := strings.TrimRight(line, " \t")
+		i := strings.LastIndexAny(s, " \t") + 1
+		key, value, ok := parseTag(s[i:], c)
+		if !ok {
+			return line, tags
+		}
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		if _, ok := tags[key]; !ok {
+			tags[key] = value
+		}
+		line = s[:i]
+	}
+}
+
+// parseTag parses the field s of the form ckey=value, the key must not be empty,
+// and s must not have quotes or backslashes.
+func parseTag(s string, c byte) (key, value string, ok bool) {
+	if len(s) < 3 || s[0] != c || strings.ContainsAny(s, `"\`) {
+		return "", "", false
+	}
+	n := strings.IndexByte(s, '=')
+	if n < 2 {
+		return "", "", false
+	}
+	return s[1:n], s[n+1:], true
+}
+
+// formatTags returns the tags as the fields of the hosts config starting with c, sorted by key.
+func formatTags(tags map[string]string, c byte) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte(c)
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(tags[k])
+	}
+	return b.String()
+}
+
+// validateTag checks whether the tag can be written in the hosts config,
+// the key must not be empty or have a "=", and neither may have white space, quotes or backslashes.
+func validateTag(key, value string) error {
+	if key == "" || strings.IndexByte(key, '=') >= 0 || strings.ContainsAny(key+value, " \t\r\n\"\\") {
+		return fmt.Errorf("%w %q", ErrInvalidTag, key+"="+value)
+	}
+	return nil
+}
+
+// copyTags returns a copy of tags.
+func copyTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	v := make(map[string]string, len(tags))
+	for k, s := range tags {
+		v[k] = s
+	}
+	return v
+}
+
+// equalTags checks whether a and b have the same tags.
+func equalTags(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if s, ok := b[k]; !ok || s != v {
+			return false
+		}
+	}
+	return true
+}
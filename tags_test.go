@@ -0,0 +1,120 @@
+package hosts
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var splitTagsTests = []struct {
+	line string
+	s    string
+	tags map[string]string
+}{
+	{"", "", nil},
+	{"192.168.1.1 example.com", "192.168.1.1 example.com", nil},
+	{"192.168.1.1 example.com #category=ads", "192.168.1.1 example.com ", map[string]string{"category": "ads"}},
+	{"192.168.1.1 example.com #a=1\t#b= ", "192.168.1.1 example.com ", map[string]string{"a": "1", "b": ""}},
+	{"192.168.1.1 example.com #a=1 #a=2", "192.168.1.1 example.com ", map[string]string{"a": "2"}},
+	{"192.168.1.1 example.com # note #a=x=y", "192.168.1.1 example.com # note ", map[string]string{"a": "x=y"}},
+	{"192.168.1.1 example.com #a=1 # note", "192.168.1.1 example.com #a=1 # note", nil},
+	{"192.168.1.1 example.com #=1", "192.168.1.1 example.com #=1", nil},
+	{"192.168.1.1 example.com #a", "192.168.1.1 example.com #a", nil},
+	{"192.168.1.1#w=2 example.com", "192.168.1.1#w=2 example.com", nil},
+	{`192.168.1.1 "example.com #a=1"`, `192.168.1.1 "example.com #a=1"`, nil},
+	{"#a=1", "", map[string]string{"a": "1"}},
+}
+
+func TestSplitTags(t *testing.T) {
+	for i, tc := range splitTagsTests {
+		s, tags := splitTags(tc.line, '#')
+		if s != tc.s || !reflect.DeepEqual(tags, tc.tags) {
+			t.Errorf("#%d test failed: split of %q should be %q %v, got %q %v", i, tc.line, tc.s, tc.tags, s, tags)
+		}
+	}
+}
+
+func TestHostsLookupByTag(t *testing.T) {
+	config := "0.0.0.0 ads.example.com #category=ads #owner=a\n" +
+		"0.0.0.0 tracker.example.com # the tracker #category=trackers\n" +
+		"0.0.0.0 ads.example.org ::  #category=ads\n" +
+		"192.168.1.1 example.com\n"
+
+	hosts := NewHosts(WithComments(true)).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("ads.example.com"); !ip.Equal(net.IPv4zero) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4zero, ip)
+	}
+
+	tests := []struct {
+		key, value string
+		names      []string
+	}{
+		{"category", "ads", []string{"ads.example.com", "ads.example.org", "ads.example.org"}},
+		{"category", "trackers", []string{"tracker.example.com"}},
+		{"owner", "a", []string{"ads.example.com"}},
+		{"owner", "", nil},
+		{"category", "malware", nil},
+	}
+	for i, tc := range tests {
+		var names []string
+		for _, host := range hosts.LookupByTag(tc.key, tc.value) {
+			names = append(names, host.Hostname)
+		}
+		if !reflect.DeepEqual(names, tc.names) {
+			t.Errorf("#%d test failed: lookup of %s=%s should be %v, got %v", i, tc.key, tc.value, tc.names, names)
+		}
+	}
+	if all := hosts.LookupByTag("category", "trackers"); len(all) != 1 || all[0].Comment != "the tracker" {
+		t.Errorf("comment should be retained along with the tags, got %#v", all)
+	}
+
+	// the tags are copied.
+	hosts.LookupByTag("category", "ads")[0].Tags["category"] = "malware"
+	if all := hosts.LookupByTag("category", "malware"); len(all) != 0 {
+		t.Errorf("tags of the table should not be modified, got %v", all)
+	}
+
+	expected := "0.0.0.0     ads.example.com #category=ads #owner=a\n" +
+		"0.0.0.0     tracker.example.com # the tracker #category=trackers\n" +
+		"0.0.0.0     ads.example.org #category=ads\n" +
+		"::          ads.example.org #category=ads\n" +
+		"192.168.1.1 example.com\n"
+	if s := hosts.String(); s != expected {
+		t.Errorf("output should be %q, got %q", expected, s)
+	}
+	reloaded := NewHosts(WithComments(true)).(*staticHosts)
+	if err := reloaded.Reload(strings.NewReader(hosts.String())); err != nil {
+		t.Fatal(err)
+	}
+	if s := reloaded.String(); s != expected {
+		t.Errorf("output of the reloaded table should be %q, got %q", expected, s)
+	}
+
+	// the tags are part of the reloaded config.
+	hosts.Reload(strings.NewReader(strings.Replace(config, "#owner=a", "#owner=b", 1)))
+	if !hosts.Changed() {
+		t.Error("reload with a changed tag should change the table")
+	}
+	if all := hosts.LookupByTag("owner", "b"); len(all) != 1 {
+		t.Errorf("lookup of owner=b should be found, got %v", all)
+	}
+}
+
+func TestHostTagsValidate(t *testing.T) {
+	host := NewHost(net.IPv4(192, 168, 1, 1), "example.com")
+	host.Tags = map[string]string{"category": "ads"}
+	if err := host.Validate(); err != nil {
+		t.Errorf("tags should be valid, got %v", err)
+	}
+	for _, tags := range []map[string]string{{"": "ads"}, {"a=b": "c"}, {"category": "a b"}, {"category": `"ads"`}} {
+		host.Tags = tags
+		if err := host.Validate(); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("tags %v should be invalid, got %v", tags, err)
+		}
+	}
+}
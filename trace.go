@@ -0,0 +1,26 @@
+package hosts
+
+import (
+	"context"
+	"net"
+)
+
+// Tracer traces the lookups of LookupContext, the hostsotel module implements it with OpenTelemetry.
+type Tracer interface {
+	// StartLookup starts the span of the lookup of host in ctx.
+	StartLookup(ctx context.Context, host string) LookupSpan
+}
+
+// LookupSpan is the span of a lookup started by Tracer.
+type LookupSpan interface {
+	// End records the result of the lookup and ends the span, a nil ip means the host is not found.
+	End(ip net.IP)
+}
+
+// lookupTraced is like LookupContext, but traces the lookup with the tracer.
+func (h *staticHosts) lookupTraced(ctx context.Context, host string) net.IP {
+	span := h.options.tracer.StartLookup(ctx, host)
+	ip := h.lookupFirst(host)
+	span.End(ip)
+	return ip
+}
@@ -0,0 +1,58 @@
+package hosts
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+type testSpan struct {
+	host  string
+	ip    net.IP
+	ended bool
+}
+
+func (t *testTracer) StartLookup(ctx context.Context, host string) LookupSpan {
+	span := &testSpan{host: host}
+	t.spans = append(t.spans, span)
+	return span
+}
+
+func (s *testSpan) End(ip net.IP) {
+	s.ip = ip
+	s.ended = true
+}
+
+func TestHostsTracer(t *testing.T) {
+	tracer := &testTracer{}
+	hosts := NewHosts(
+		WithInitialHosts(NewHost(net.IPv4(192, 168, 1, 1), "example.com")),
+		WithTracer(tracer),
+	).(*staticHosts)
+
+	hosts.LookupContext(context.Background(), "example.com")
+	hosts.Lookup("example.org")
+	hosts.LookupAll("example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hosts.LookupContext(ctx, "example.com")
+
+	tests := []testSpan{
+		{host: "example.com", ip: net.IPv4(192, 168, 1, 1), ended: true},
+		{host: "example.org", ended: true},
+	}
+	if len(tracer.spans) != len(tests) {
+		t.Fatalf("spans should be %d, got %d", len(tests), len(tracer.spans))
+	}
+	for i, tc := range tests {
+		span := tracer.spans[i]
+		if span.host != tc.host || !span.ip.Equal(tc.ip) || span.ended != tc.ended {
+			t.Errorf("#%d test failed: span should be %+v, got %+v", i, tc, *span)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package hosts
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// cacheValidators is the validators of the last response of ReloadURL.
+type cacheValidators struct {
+	url          string
+	etag         string
+	lastModified string
+}
+
+// ReloadURL is like Reload, but parses config from the body of the HTTP GET response of url.
+// The request is bound to ctx, so the timeout of the request is taken from ctx.
+// The ETag and Last-Modified of the last response are sent with the next request of the same url,
+// and the reload is skipped if the server responds that the config is not modified.
+// An error is returned for a response other than 200 OK, leaving the host table unchanged.
+// A gzip-compressed body is detected by its header and decompressed.
+// The body is not trusted: the include option is refused with ErrIncludeRemote, so that a remote config cannot read the local files,
+// and the references to variables are not replaced regardless of WithExpand.
+func (h *staticHosts) ReloadURL(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	h.mux.RLock()
+	validators := h.validators
+	h.mux.RUnlock()
+
+	if validators.url == url {
+		if validators.etag != "" {
+			req.Header.Set("If-None-Match", validators.etag)
+		}
+		if validators.lastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.lastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.reloadFailed(err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil
+	default:
+		err := fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+		h.reloadFailed(err)
+		return err
+	}
+
+	applied, err := h.reloadRemote(bufio.NewReader(resp.Body))
+	if err != nil || !applied {
+		return err // the validators are kept for a body not applied, such as after Stop
+	}
+
+	h.mux.Lock()
+	h.validators = cacheValidators{
+		url:          url,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	h.mux.Unlock()
+
+	return nil
+}
+
+// reloadRemote is like Reload, but parses config from r as a remote config, decompressing it if it is gzip data.
+// It returns false if the reload is dropped because the reloader is stopped.
+func (h *staticHosts) reloadRemote(r *bufio.Reader) (bool, error) {
+	compressed := isGzip(r)
+	applied := false
+	err := h.reload(func(p *parser) error {
+		applied = true
+		p.setRemote()
+		if compressed {
+			return parseGzip(p, r)
+		}
+		return p.parse(r)
+	})
+	return applied, err
+}
@@ -0,0 +1,157 @@
+package hosts
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostsReloadURL(t *testing.T) {
+	var requests, fails int32
+	body := "192.168.1.1 example.com"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&fails) > 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	hosts := NewHosts().(*staticHosts)
+	var reloads int32
+	hosts.OnReload(func(int) {
+		atomic.AddInt32(&reloads, 1)
+	})
+
+	if err := hosts.ReloadURL(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+
+	if err := hosts.ReloadURL(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&reloads); n != 1 {
+		t.Errorf("reloads should be %d for a not modified config, got %d", 1, n)
+	}
+
+	atomic.StoreInt32(&fails, 1)
+	if err := hosts.ReloadURL(context.Background(), srv.URL+"/other"); err == nil {
+		t.Error("reload should fail for an unexpected status")
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+	if err := hosts.ReloadURL(ctx, srv.URL); err == nil {
+		t.Error("reload should fail for a timed out context")
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("requests should be %d, got %d", 3, n)
+	}
+}
+
+func TestHostsReloadURLStopped(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("192.168.1.1 example.com"))
+	}))
+	defer srv.Close()
+
+	hosts := NewHosts().(*staticHosts)
+	hosts.Stop()
+	if err := hosts.ReloadURL(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); ip != nil {
+		t.Errorf("reload after stop should be dropped, got %s", ip)
+	}
+
+	// the dropped body is requested again rather than reported as not modified.
+	hosts.Start()
+	defer hosts.Stop()
+	if err := hosts.ReloadURL(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s after start, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+}
+
+func TestHostsReloadURLRemote(t *testing.T) {
+	f, err := ioutil.TempFile("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("root:secret 192.168.1.3 example.net\n")
+	f.Close()
+	os.Setenv("HOSTS_TEST_NAME", "secret")
+	defer os.Unsetenv("HOSTS_TEST_NAME")
+
+	config := "include " + f.Name() + "\n" +
+		"192.168.1.1 example.com\n" +
+		"192.168.1.2 ${HOSTS_TEST_NAME}.example.org\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hosts.gz" {
+			w.Write(gzipData(t, config))
+			return
+		}
+		w.Write([]byte(config))
+	}))
+	defer srv.Close()
+
+	for i, url := range []string{srv.URL, srv.URL + "/hosts.gz"} {
+		hosts := NewHosts().(*staticHosts)
+		if err := hosts.ReloadURL(context.Background(), url); err != nil {
+			t.Fatal(err)
+		}
+		if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+			t.Errorf("#%d test failed: lookup should be %s, got %s", i, net.IPv4(192, 168, 1, 1), ip)
+		}
+		if ip := hosts.Lookup("example.net"); ip != nil {
+			t.Errorf("#%d test failed: included file should not be read, got %s", i, ip)
+		}
+		if ip := hosts.Lookup("secret.example.org"); ip != nil {
+			t.Errorf("#%d test failed: variables should not be expanded, got %s", i, ip)
+		}
+		errs := hosts.Errors()
+		if len(errs) != 2 || !errors.Is(errs[0].Err, ErrIncludeRemote) || errs[0].Line != 1 {
+			t.Fatalf("#%d test failed: include should be refused, got %v", i, errs)
+		}
+		for _, e := range errs {
+			if strings.Contains(e.Error(), "root:secret") || strings.Contains(e.Error(), "secret.example.org") {
+				t.Errorf("#%d test failed: error should not leak the local content, got %v", i, e)
+			}
+		}
+	}
+
+	hosts := NewHosts(WithStrict()).(*staticHosts)
+	if err := hosts.ReloadURL(context.Background(), srv.URL); !errors.Is(err, ErrIncludeRemote) {
+		t.Errorf("strict reload should fail with %v, got %v", ErrIncludeRemote, err)
+	}
+}
@@ -0,0 +1,97 @@
+package hosts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxNameLength is the maximum length of a domain name in its ASCII form, without the trailing dot.
+const maxNameLength = 253
+
+// maxLabelLength is the maximum length of a label of a domain name.
+const maxLabelLength = 63
+
+// Validate checks whether the entry is valid, that is, the IP is not nil,
+// and the hostname and aliases are valid domain names per RFC 1123.
+// Internationalized domain names are checked in their ASCII form,
+// names may be wildcards (*.example.com) or patterns (re:expr).
+// The returned error names the invalid field.
+func (h Host) Validate() error {
+	return h.validate(false)
+}
+
+// validate is like Validate, underscore allows the underscores in the labels of the names.
+func (h Host) validate(underscore bool) error {
+	if h.IP == nil {
+		return fmt.Errorf("IP: %w: nil", ErrInvalidIP)
+	}
+	if err := validateName(h.Hostname, underscore); err != nil {
+		return fmt.Errorf("Hostname: %w", err)
+	}
+	for i, alias := range h.Aliases {
+		if err := validateName(alias, underscore); err != nil {
+			return fmt.Errorf("Aliases[%d]: %w", i, err)
+		}
+	}
+	if h.Weight < 0 {
+		return fmt.Errorf("Weight: %w: %d", ErrInvalidWeight, h.Weight)
+	}
+	for key, value := range h.Tags {
+		if err := validateTag(key, value); err != nil {
+			return fmt.Errorf("Tags: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateName checks whether name is a valid hostname or alias, underscore allows the underscores in its labels.
+func validateName(name string, underscore bool) error {
+	if name == catchAllName {
+		return nil
+	}
+	if isPattern(name) {
+		if _, err := compilePattern(name, false); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidPattern, name, err)
+		}
+		return nil
+	}
+
+	s := strings.TrimPrefix(name, "*.")
+	s, err := toASCII(s)
+	if err == nil {
+		err = checkDomainName(trimDot(s), underscore)
+	}
+	if err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidName, name, err)
+	}
+	return nil
+}
+
+// checkDomainName checks the lower case ASCII name against the rules of RFC 1123:
+// the labels consist of letters, digits and hyphens, and do not begin or end with a hyphen.
+// underscore also allows underscores in the labels, which are common in the internal names.
+func checkDomainName(name string, underscore bool) error {
+	if name == "" {
+		return errors.New("empty name")
+	}
+	if len(name) > maxNameLength {
+		return fmt.Errorf("name longer than %d characters", maxNameLength)
+	}
+	for _, label := range strings.Split(name, ".") {
+		switch {
+		case label == "":
+			return errors.New("empty label")
+		case len(label) > maxLabelLength:
+			return fmt.Errorf("label %q longer than %d characters", label, maxLabelLength)
+		case label[0] == '-' || label[len(label)-1] == '-':
+			return fmt.Errorf("label %q begins or ends with a hyphen", label)
+		}
+		for i := 0; i < len(label); i++ {
+			if c := label[i]; !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' && underscore) {
+				return fmt.Errorf("invalid character %q in label %q", c, label)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package hosts
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+var hostValidateTests = []struct {
+	host  Host
+	field string
+	err   error
+}{
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example", "www.example.com."), "", nil},
+	{NewHost(net.IPv4(192, 168, 1, 1), "*.example.com", "münchen.de", `re:ads\d+`), "", nil},
+	{NewHost(net.IPv4(192, 168, 1, 1), "a-1.EXAMPLE.com"), "", nil},
+	{NewHost(net.IPv4(192, 168, 1, 1), strings.Repeat("a", 63)+".com"), "", nil},
+	{NewHost(nil, "example.com"), "IP", ErrInvalidIP},
+	{NewHost(net.IPv4(192, 168, 1, 1), ""), "Hostname", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), "."), "Hostname", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example..com"), "Hostname", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), "-example.com"), "Hostname", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example-.com"), "Hostname", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), "foo_bar.com"), "Hostname", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), "foo\x00bar"), "Hostname", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), strings.Repeat("a", 64)+".com"), "Hostname", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), strings.Repeat("a.", 127)+"com"), "Hostname", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example", "a b"), "Aliases[1]", ErrInvalidName},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com", "re:ads("), "Aliases[0]", ErrInvalidPattern},
+	{NewHost(net.IPv4(192, 168, 1, 1), "glob:[ads", "example.com"), "Hostname", ErrInvalidPattern},
+}
+
+func TestHostValidate(t *testing.T) {
+	for i, tc := range hostValidateTests {
+		err := tc.host.Validate()
+		if tc.err == nil {
+			if err != nil {
+				t.Errorf("#%d test failed: error should be nil, got %v", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tc.err) || !strings.HasPrefix(err.Error(), tc.field+": ") {
+			t.Errorf("#%d test failed: error should be %s: %v, got %v", i, tc.field, tc.err, err)
+		}
+	}
+}
+
+func TestHostsAddInvalid(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Add(NewHost(nil, "example.com")); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("error should be %v, got %v", ErrInvalidIP, err)
+	}
+	if err := hosts.Add(NewHost(net.IPv4(192, 168, 1, 1), "foo bar")); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("error should be %v, got %v", ErrInvalidName, err)
+	}
+	if err := hosts.Set(NewHost(net.IPv4(192, 168, 1, 1), "foo bar")); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("set error should be %v, got %v", ErrInvalidName, err)
+	}
+	if err := hosts.Set(Host{IP: net.IPv4(192, 168, 1, 1), Hostname: "example.com", Weight: -1}); !errors.Is(err, ErrInvalidWeight) {
+		t.Errorf("set error should be %v, got %v", ErrInvalidWeight, err)
+	}
+	if err := hosts.Pin(Host{IP: net.IPv4(192, 168, 1, 1), Hostname: "example.com", Tags: map[string]string{"a b": "c"}}); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("pin error should be %v, got %v", ErrInvalidTag, err)
+	}
+	if n := hosts.Count(); n != 0 || len(hosts.Pinned()) != 0 {
+		t.Errorf("count should be 0 with no pinned entries, got %d and %v", n, hosts.Pinned())
+	}
+}
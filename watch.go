@@ -0,0 +1,138 @@
+package hosts
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// watchDelay is the time to wait for successive file events to settle before reloading.
+	watchDelay = 200 * time.Millisecond
+	// watchAttempts is the number of times to check for a watched file momentarily absent, watchDelay apart.
+	watchAttempts = 10
+)
+
+// Watch loads the hosts file at path, then reloads it each time the file is written or replaced,
+// until the reloader is stopped.
+// The directory of the file is watched, so that files saved by an atomic rename are also detected,
+// and the file is opened again by path on each reload, so that the replacing file is read.
+// A file momentarily absent, such as renamed to a backup before being saved again, is waited for,
+// and the host table is left as is until the file is parsed successfully.
+func (h *staticHosts) Watch(path string) error {
+	path = filepath.Clean(path)
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	if err := h.ReloadFile(path); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	done := h.done()
+	match := func(name string) bool {
+		return name == path
+	}
+	reload := func() {
+		if waitFile(path, done) {
+			h.ReloadFile(path)
+		}
+	}
+	h.spawn(func() { h.watch(watcher, match, reload, done) })
+
+	return nil
+}
+
+// WatchDir loads the files in dir whose names match glob, like ReloadDir,
+// then reloads them each time one of them is added, removed, written or replaced, until the reloader is stopped.
+func (h *staticHosts) WatchDir(dir, glob string) error {
+	dir = filepath.Clean(dir)
+	if _, err := filepath.Match(glob, ""); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	if err := h.ReloadDir(dir, glob); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	match := func(name string) bool {
+		ok, _ := filepath.Match(glob, filepath.Base(name))
+		return ok && filepath.Dir(name) == dir
+	}
+	reload := func() {
+		h.ReloadDir(dir, glob)
+	}
+	done := h.done()
+	h.spawn(func() { h.watch(watcher, match, reload, done) })
+
+	return nil
+}
+
+// watch calls reload after the events of the files matching match settle.
+func (h *staticHosts) watch(watcher *fsnotify.Watcher, match func(name string) bool, reload func(), done <-chan struct{}) {
+	defer watcher.Close()
+
+	timer := time.NewTimer(watchDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !match(filepath.Clean(event.Name)) ||
+				event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				break
+			}
+			timer.Reset(watchDelay)
+		case _, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+		case <-timer.C:
+			reload()
+		case <-done:
+			return
+		}
+	}
+}
+
+// waitFile waits for the file at path to exist, for at most watchAttempts checks.
+// It returns false if done is closed meanwhile.
+func waitFile(path string, done <-chan struct{}) bool {
+	for i := 1; i < watchAttempts; i++ {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			return true
+		}
+		select {
+		case <-time.After(watchDelay):
+		case <-done:
+			return false
+		}
+	}
+	return true
+}
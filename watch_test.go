@@ -0,0 +1,209 @@
+package hosts
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHostsWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hosts")
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+
+	if err := hosts.Watch(path); err == nil {
+		t.Error("watch should fail for a missing file")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("192.168.1.1 example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := hosts.Watch(path); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("192.168.1.2 example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 2))
+
+	tmp := filepath.Join(dir, "hosts.tmp")
+	if err := ioutil.WriteFile(tmp, []byte("192.168.1.3 example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 3))
+}
+
+func TestHostsWatchAtomicRename(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hosts")
+	if err := ioutil.WriteFile(path, []byte("192.168.1.1 example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hosts := NewHosts().(*staticHosts)
+	defer hosts.Stop()
+	if err := hosts.Watch(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// saves by writing a temporary file renamed over the file, several times in a row
+	for i := 2; i <= 4; i++ {
+		tmp := filepath.Join(dir, fmt.Sprintf(".hosts.swp%d", i))
+		config := fmt.Sprintf("192.168.1.%d example.com", i)
+		if err := ioutil.WriteFile(tmp, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 4))
+
+	// saves by renaming the file to a backup, then writing a new file after a while
+	if err := os.Rename(path, path+"~"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * watchDelay)
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 4)) {
+		t.Errorf("lookup should be %s while the file is absent, got %s", net.IPv4(192, 168, 1, 4), ip)
+	}
+	if err := ioutil.WriteFile(path, []byte("192.168.1.5 example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 5))
+
+	if n := hosts.Stats().ReloadErrors; n != 0 {
+		t.Errorf("reloads should not fail, got %d errors", n)
+	}
+}
+
+func TestHostsWatchDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"10-a.hosts": "192.168.1.1 a.example.com example.com",
+		"20-b.hosts": "192.168.1.2 b.example.com example.com",
+		"b.conf":     "192.168.1.3 c.example.com",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hosts := NewHosts(WithConflictStrategy(ConflictLastWins)).(*staticHosts)
+	defer hosts.Stop()
+
+	if err := hosts.WatchDir(dir, "["); err == nil {
+		t.Error("watch should fail for a malformed glob")
+	}
+	if err := hosts.WatchDir(filepath.Join(dir, "missing"), "*.hosts"); err == nil {
+		t.Error("watch should fail for a missing directory")
+	}
+
+	if err := hosts.WatchDir(dir, "*.hosts"); err != nil {
+		t.Fatal(err)
+	}
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 2)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 2), ip)
+	}
+	if ip := hosts.Lookup("c.example.com"); ip != nil {
+		t.Errorf("lookup of a file not matching should be nil, got %s", ip)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "30-c.hosts"), []byte("192.168.1.3 example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 3))
+
+	if err := os.Remove(filepath.Join(dir, "30-c.hosts")); err != nil {
+		t.Fatal(err)
+	}
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 2))
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "20-b.hosts"), []byte("192.168.1.4 b.example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitLookup(t, hosts, "example.com", net.IPv4(192, 168, 1, 1))
+	waitLookup(t, hosts, "b.example.com", net.IPv4(192, 168, 1, 4))
+}
+
+// waitLookup waits for the lookup of host to return ip.
+func waitLookup(t *testing.T, hosts Hosts, host string, ip net.IP) {
+	t.Helper()
+
+	var v net.IP
+	for i := 0; i < 50; i++ {
+		if v = hosts.Lookup(host); v.Equal(ip) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Errorf("lookup should be %s, got %s", ip, v)
+}
+
+func TestHostsWatchWait(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hosts")
+	if err := ioutil.WriteFile(path, []byte("192.168.1.1 example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Watch(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := hosts.WatchDir(dir, "hosts*"); err != nil {
+		t.Fatal(err)
+	}
+	hosts.Stop()
+
+	waited := make(chan struct{})
+	go func() {
+		hosts.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("wait should return after the watching goroutines exit")
+	}
+
+	// the file is no longer watched.
+	if err := ioutil.WriteFile(path, []byte("192.168.1.2 example.com"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * watchDelay)
+	if ip := hosts.Lookup("example.com"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s after stop, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+}
@@ -0,0 +1,49 @@
+package hosts
+
+import (
+	"net"
+	"time"
+)
+
+// LookupWeighted is like Lookup, but picks one of the addresses of host at random in proportion to their weights,
+// the addresses without a weight have the weight 1.
+// The weight of an address with more than one entry is the one of its first entry.
+// The random numbers are drawn from the source set by WithRandom.
+func (h *staticHosts) LookupWeighted(host string) net.IP {
+	if h == nil || host == "" {
+		return nil
+	}
+
+	start := h.startLookup()
+	t := h.load()
+	var ips []net.IP
+	var weights []int
+	total := 0
+	var now time.Time
+	for _, i := range h.lookupIndexes(t, host) {
+		v := &t.hosts[i]
+		if v.expired(&now) || containsIP(ips, v.IP) {
+			continue
+		}
+		w := v.Weight
+		if w <= 0 {
+			w = 1
+		}
+		ips = append(ips, v.IP)
+		weights = append(weights, w)
+		total += w
+	}
+	h.counters.lookup(len(ips) > 0, start)
+	if len(ips) == 0 {
+		return nil
+	}
+
+	n := h.options.intn(total)
+	for i, w := range weights {
+		if n < w {
+			return copyIP(ips[i])
+		}
+		n -= w
+	}
+	return copyIP(ips[len(ips)-1])
+}
@@ -0,0 +1,78 @@
+package hosts
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestHostsLookupWeighted(t *testing.T) {
+	var n int
+	hosts := NewHosts(WithRandom(func(total int) int {
+		v := n % total
+		n++
+		return v
+	})).(*staticHosts)
+
+	config := "10.0.0.1#w=3 10.0.0.2 example.com\n10.0.0.3#w=2 example.org # 10.0.0.4#w=9\n10.0.0.1#w=5 example.com www.example.com"
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := hosts.Errors(); len(errs) != 0 {
+		t.Fatalf("reload should not have errors, got %v", errs)
+	}
+
+	// the weights of example.com are 3 and 1, the second entry of 10.0.0.1 is ignored
+	expected := []net.IP{
+		net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2),
+		net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2),
+	}
+	for i, ip := range expected {
+		if v := hosts.LookupWeighted("example.com"); !v.Equal(ip) {
+			t.Errorf("#%d test failed: lookup should be %s, got %s", i, ip, v)
+		}
+	}
+	if ip := hosts.LookupWeighted("example.org"); !ip.Equal(net.IPv4(10, 0, 0, 3)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(10, 0, 0, 3), ip)
+	}
+	if ip := hosts.LookupWeighted("example.net"); ip != nil {
+		t.Errorf("lookup should be nil, got %s", ip)
+	}
+
+	var b bytes.Buffer
+	if _, err := hosts.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); !strings.Contains(s, "10.0.0.1#w=3 example.com\n") || !strings.Contains(s, "10.0.0.3#w=2 example.org\n") {
+		t.Errorf("output should have the weights, got %q", s)
+	}
+	if err := hosts.Reload(&b); err != nil {
+		t.Fatal(err)
+	}
+	if host, _ := hosts.GetHost("example.org"); host.Weight != 2 {
+		t.Errorf("weight should be 2 after reload of the output, got %d", host.Weight)
+	}
+}
+
+func TestHostsReloadInvalidWeight(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	config := "10.0.0.1#w=0 example.com\n10.0.0.2#w=x example.com\nexample.com#w=2 10.0.0.3\n10.0.0.4#w=-1 example.com"
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	errs := hosts.Errors()
+	if len(errs) != 4 {
+		t.Fatalf("errors should be 4, got %v", errs)
+	}
+	for i, e := range errs {
+		if !errors.Is(e.Err, ErrInvalidWeight) {
+			t.Errorf("#%d test failed: error should be %v, got %v", i, ErrInvalidWeight, e.Err)
+		}
+	}
+
+	if err := (Host{IP: net.IPv4(10, 0, 0, 1), Hostname: "example.com", Weight: -1}).Validate(); !errors.Is(err, ErrInvalidWeight) {
+		t.Errorf("validate should fail with %v, got %v", ErrInvalidWeight, err)
+	}
+}
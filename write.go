@@ -0,0 +1,136 @@
+package hosts
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteTo writes the host table to w in the hosts config format, which can be parsed back by Reload.
+// The entries are written in the order they are added, one per line, followed by the networks, the cname options and the negated names,
+// entries without an IP or hostname are skipped. The fields are separated by the first of the separators set by WithSeparators.
+// The output is written from a single version of the host table, so a concurrent reload never tears it.
+func (h *staticHosts) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+
+	fs := h.options.fieldSeparator()
+	h.mux.RLock()
+	if h.period > 0 {
+		b.WriteString("reload")
+		b.WriteByte(fs)
+		b.WriteString(h.period.String())
+		b.WriteByte('\n')
+	}
+	t := h.load() // the version of the table of the period, which is never modified
+	h.mux.RUnlock()
+	writeHosts(&b, t.hosts, h.options.commentChar, fs)
+	writeNets(&b, t.nets, fs)
+	writeCNAMEs(&b, t.cnames, fs)
+	writeNegations(&b, t.negated)
+
+	n, err := w.Write(b.Bytes())
+	return int64(n), err
+}
+
+// String returns the host table in the hosts config format.
+func (h *staticHosts) String() string {
+	var b strings.Builder
+	h.WriteTo(&b)
+	return b.String()
+}
+
+// writeHosts writes hosts to b with the fields separated by fs, the comments start with c.
+// The hostnames are aligned if fs is a space.
+func writeHosts(b *bytes.Buffer, hosts []Host, c, fs byte) {
+	width := 0
+	for _, host := range hosts {
+		if fs != ' ' {
+			break
+		}
+		if host.IP == nil || host.Hostname == "" {
+			continue
+		}
+		if n := len(host.addr()); n > width {
+			width = n
+		}
+	}
+
+	var ttl time.Duration
+	for _, host := range hosts {
+		if host.IP == nil || host.Hostname == "" {
+			continue
+		}
+		if host.TTL != ttl {
+			ttl = host.TTL
+			b.WriteString("ttl")
+			b.WriteByte(fs)
+			b.WriteString(ttl.String())
+			b.WriteByte('\n')
+		}
+		writeHost(b, host, width, c, fs)
+		b.WriteByte('\n')
+	}
+}
+
+// String returns the entry as a line of the hosts config, "IP hostname [aliases...] [# comment] [#key=value...]",
+// which can be parsed back by Reload. The IP field is omitted if the IP is nil.
+func (h Host) String() string {
+	var b bytes.Buffer
+	writeHost(&b, h, 0, '#', ' ')
+	return b.String()
+}
+
+// writeHost writes the fields of host to b separated by fs, the IP is padded to width, the comment and tags start with c.
+func writeHost(b *bytes.Buffer, host Host, width int, c, fs byte) {
+	sep := false
+	if host.IP != nil {
+		ip := host.addr()
+		if host.Weight > 0 {
+			ip += weightPrefix + strconv.Itoa(host.Weight)
+		}
+		b.WriteString(ip)
+		if n := width - len(ip); n > 0 {
+			b.WriteString(strings.Repeat(" ", n))
+		}
+		sep = true
+	}
+	for _, name := range append([]string{host.Hostname}, host.Aliases...) {
+		if name == "" {
+			continue
+		}
+		if sep {
+			b.WriteByte(fs)
+		}
+		b.WriteString(name)
+		sep = true
+	}
+	if host.Comment != "" {
+		if sep {
+			b.WriteByte(' ')
+		}
+		b.WriteByte(c)
+		b.WriteByte(' ')
+		b.WriteString(host.Comment)
+		sep = true
+	}
+	if len(host.Tags) > 0 {
+		if sep {
+			b.WriteByte(' ')
+		}
+		b.WriteString(formatTags(host.Tags, c))
+	}
+}
+
+// writeNets writes the networks to b, one per line with the fields separated by fs.
+func writeNets(b *bytes.Buffer, nets []netHost, fs byte) {
+	for _, v := range nets {
+		b.WriteString(v.Net.String())
+		for _, name := range v.Names {
+			b.WriteByte(fs)
+			b.WriteString(name)
+		}
+		b.WriteByte('\n')
+	}
+}
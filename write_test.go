@@ -0,0 +1,226 @@
+package hosts
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostsWriteTo(t *testing.T) {
+	hosts := NewHosts(WithInitialHosts(
+		NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example"),
+		NewHost(nil, "example.org"),
+		NewHost(net.ParseIP("2001:db8::1"), "example.com"),
+		NewHost(net.IPv4(10, 0, 0, 1), "Example.net", "a", "b"),
+		NewHost(net.IPv4(10, 0, 0, 2), ""),
+	)).(*staticHosts)
+
+	expected := "192.168.1.1 example.com example\n" +
+		"2001:db8::1 example.com\n" +
+		"10.0.0.1    Example.net a b\n"
+
+	var b bytes.Buffer
+	n, err := hosts.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(b.Len()) {
+		t.Errorf("written bytes should be %d, got %d", b.Len(), n)
+	}
+	if b.String() != expected {
+		t.Errorf("output should be %q, got %q", expected, b.String())
+	}
+	if s := hosts.String(); s != expected {
+		t.Errorf("output should be %q, got %q", expected, s)
+	}
+
+	reloaded := NewHosts().(*staticHosts)
+	if err := reloaded.Reload(strings.NewReader("reload 10s\n" + expected)); err != nil {
+		t.Fatal(err)
+	}
+	if s, expected := reloaded.String(), "reload 10s\n"+expected; s != expected {
+		t.Errorf("output should be %q, got %q", expected, s)
+	}
+}
+
+func TestHostsWriteToSeparators(t *testing.T) {
+	config := "reload,10s\n" +
+		"ttl,1m\n" +
+		"192.168.1.1,example.com,example\n" +
+		"10.0.0.0/8,internal.example.com\n" +
+		"cname,example.com,www.example.com\n"
+	hosts := NewHosts(WithSeparators(",")).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "reload,10s\n" +
+		"ttl,1m0s\n" +
+		"192.168.1.1,example.com,example\n" +
+		"10.0.0.0/8,internal.example.com\n" +
+		"cname,example.com,www.example.com\n"
+	if s := hosts.String(); s != expected {
+		t.Errorf("output should be %q, got %q", expected, s)
+	}
+
+	reloaded := NewHosts(WithSeparators(",")).(*staticHosts)
+	if err := reloaded.Reload(strings.NewReader(hosts.String())); err != nil {
+		t.Fatal(err)
+	}
+	if errs := reloaded.Errors(); len(errs) > 0 {
+		t.Errorf("reload of the output should have no errors, got %v", errs)
+	}
+	if s := reloaded.String(); s != expected {
+		t.Errorf("output should be %q, got %q", expected, s)
+	}
+}
+
+var hostStringTests = []struct {
+	host Host
+	s    string
+}{
+	{Host{}, ""},
+	{NewHost(net.IPv4(192, 168, 1, 1), "example.com"), "192.168.1.1 example.com"},
+	{NewHost(net.ParseIP("2001:db8::1"), "example.com", "a", "b"), "2001:db8::1 example.com a b"},
+	{NewHost(nil, "example.com", "a"), "example.com a"},
+	{NewHost(net.IPv4(192, 168, 1, 1), "", "a"), "192.168.1.1 a"},
+	{NewHost(net.IPv4(192, 168, 1, 1), ""), "192.168.1.1"},
+}
+
+func TestHostString(t *testing.T) {
+	for i, tc := range hostStringTests {
+		if s := tc.host.String(); s != tc.s {
+			t.Errorf("#%d test failed: string should be %q, got %q", i, tc.s, s)
+		}
+	}
+
+	host := NewHost(net.IPv4(192, 168, 1, 1), "example.com", "example")
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(host.String())); err != nil {
+		t.Fatal(err)
+	}
+	if all := hosts.GetAll(); len(all) != 1 || all[0].String() != host.String() {
+		t.Errorf("reloaded hosts should be %v, got %v", []Host{host}, all)
+	}
+}
+
+func TestHostsComments(t *testing.T) {
+	config := "; hosts\n" +
+		"192.168.1.1 example.com example ; the example\n" +
+		"192.168.1.2 example.org;\n" +
+		"#192.168.1.3 example.net\n"
+
+	hosts := NewHosts(WithCommentChar(';'), WithComments(true)).(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := hosts.Errors(); len(errs) != 1 || errs[0].Line != 4 {
+		t.Errorf("errors should be line 4, got %v", errs)
+	}
+	if ip := hosts.Lookup("example"); !ip.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("lookup should be %s, got %s", net.IPv4(192, 168, 1, 1), ip)
+	}
+	all := hosts.GetAll()
+	if len(all) != 2 || all[0].Comment != "the example" || all[1].Comment != "" {
+		t.Fatalf("comments should be retained, got %#v", all)
+	}
+
+	expected := "192.168.1.1 example.com example ; the example\n" +
+		"192.168.1.2 example.org\n"
+	if s := hosts.String(); s != expected {
+		t.Errorf("output should be %q, got %q", expected, s)
+	}
+	if s, expected := all[0].String(), "192.168.1.1 example.com example # the example"; s != expected {
+		t.Errorf("string should be %q, got %q", expected, s)
+	}
+
+	// the comments are part of the reloaded config.
+	var reloads int
+	hosts.OnReload(func(int) { reloads++ })
+	hosts.Reload(strings.NewReader(config))
+	hosts.Reload(strings.NewReader(strings.Replace(config, "the example", "an example", 1)))
+	if reloads != 1 {
+		t.Errorf("reloads should be 1, got %d", reloads)
+	}
+
+	hosts = NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("192.168.1.1 example.com # the example")); err != nil {
+		t.Fatal(err)
+	}
+	if all := hosts.GetAll(); len(all) != 1 || all[0].Comment != "" {
+		t.Errorf("comments should be dropped, got %#v", all)
+	}
+}
+
+func TestHostsWriteToConcurrent(t *testing.T) {
+	var configs [2]string
+	for i, prefix := range []string{"a", "b"} {
+		var b strings.Builder
+		fmt.Fprintf(&b, "reload %ds\n", i+1)
+		for j := 0; j < 50*(i+1); j++ {
+			fmt.Fprintf(&b, "10.%d.0.%d %s%d.example.com\n", i, j, prefix, j)
+		}
+		configs[i] = b.String()
+	}
+
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader(configs[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err := hosts.Reload(strings.NewReader(configs[i%2])); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				var b bytes.Buffer
+				if _, err := hosts.WriteTo(&b); err != nil {
+					t.Error(err)
+					return
+				}
+				lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+				g := 0
+				if lines[0] == "reload 2s" {
+					g = 1
+				}
+				if len(lines) != 50*(g+1)+1 {
+					t.Errorf("output should have %d lines, got %d", 50*(g+1)+1, len(lines))
+					return
+				}
+				for k, line := range lines[1:] {
+					expected := fmt.Sprintf("%s%d.example.com", []string{"a", "b"}[g], k)
+					if f := strings.Fields(line); len(f) != 2 || f[1] != expected {
+						t.Errorf("line %d should be of %s, got %q", k+2, expected, line)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(done)
+	wg.Wait()
+}
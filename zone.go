@@ -0,0 +1,61 @@
+package hosts
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// LookupIPAddr is like LookupAll, but returns the addresses with their IPv6 zones,
+// such as fe80::1%eth0, which are needed to reach the link-local addresses.
+func (h *staticHosts) LookupIPAddr(host string) []net.IPAddr {
+	if h == nil || host == "" {
+		return nil
+	}
+
+	start := h.startLookup()
+	t := h.load()
+	var addrs []net.IPAddr
+	var now time.Time
+	for _, i := range h.lookupIndexes(t, host) {
+		v := &t.hosts[i]
+		if v.expired(&now) {
+			continue
+		}
+		addrs = appendAddr(addrs, net.IPAddr{IP: v.IP, Zone: v.Zone})
+	}
+	h.counters.lookup(len(addrs) > 0, start)
+	return addrs
+}
+
+// appendAddr appends addr to addrs if it is not already present.
+func appendAddr(addrs []net.IPAddr, addr net.IPAddr) []net.IPAddr {
+	for _, v := range addrs {
+		if v.IP.Equal(addr.IP) && v.Zone == addr.Zone {
+			return addrs
+		}
+	}
+	return append(addrs, net.IPAddr{IP: copyIP(addr.IP), Zone: addr.Zone})
+}
+
+// parseIPZone parses s as an IP address with an optional IPv6 zone, such as fe80::1%eth0.
+// A nil IP is returned if s is not a valid address.
+func parseIPZone(s string) (net.IP, string) {
+	n := strings.LastIndexByte(s, '%')
+	if n < 0 {
+		return net.ParseIP(s), ""
+	}
+	ip, zone := net.ParseIP(s[:n]), s[n+1:]
+	if ip == nil || ip.To4() != nil || zone == "" {
+		return nil, ""
+	}
+	return ip, zone
+}
+
+// addr returns the IP of h with its zone.
+func (h Host) addr() string {
+	if h.Zone == "" {
+		return h.IP.String()
+	}
+	return h.IP.String() + "%" + h.Zone
+}
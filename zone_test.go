@@ -0,0 +1,72 @@
+package hosts
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+var parseIPZoneTests = []struct {
+	s    string
+	ip   net.IP
+	zone string
+}{
+	{"192.168.1.1", net.IPv4(192, 168, 1, 1), ""},
+	{"fe80::1", net.ParseIP("fe80::1"), ""},
+	{"fe80::1%eth0", net.ParseIP("fe80::1"), "eth0"},
+	{"fe80::1%", nil, ""},
+	{"192.168.1.1%eth0", nil, ""},
+	{"example.com%eth0", nil, ""},
+	{"example.com", nil, ""},
+}
+
+func TestParseIPZone(t *testing.T) {
+	for i, tc := range parseIPZoneTests {
+		ip, zone := parseIPZone(tc.s)
+		if !ip.Equal(tc.ip) || zone != tc.zone {
+			t.Errorf("#%d test failed: address should be %s%%%s, got %s%%%s", i, tc.ip, tc.zone, ip, zone)
+		}
+	}
+}
+
+func TestHostsZone(t *testing.T) {
+	hosts := NewHosts().(*staticHosts)
+	if err := hosts.Reload(strings.NewReader("fe80::1%eth0 router.lan\nfe80::1%eth1 router.lan\n192.168.1.1 router.lan")); err != nil {
+		t.Fatal(err)
+	}
+	if errs := hosts.Errors(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if ip := hosts.Lookup("router.lan"); !ip.Equal(net.ParseIP("fe80::1")) {
+		t.Errorf("lookup should be %s, got %s", net.ParseIP("fe80::1"), ip)
+	}
+	addrs := hosts.LookupIPAddr("router.lan")
+	expected := []string{"fe80::1%eth0", "fe80::1%eth1", "192.168.1.1"}
+	if len(addrs) != len(expected) {
+		t.Fatalf("addresses should be %v, got %v", expected, addrs)
+	}
+	for i := range addrs {
+		if addrs[i].String() != expected[i] {
+			t.Errorf("#%d test failed: address should be %s, got %s", i, expected[i], addrs[i].String())
+		}
+	}
+
+	all := hosts.GetAll()
+	if s := all[0].String(); s != "fe80::1%eth0 router.lan" {
+		t.Errorf("string should be %q, got %q", "fe80::1%eth0 router.lan", s)
+	}
+
+	b, err := json.Marshal(all[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var host Host
+	if err := json.Unmarshal(b, &host); err != nil {
+		t.Fatal(err)
+	}
+	if !host.IP.Equal(all[0].IP) || host.Zone != "eth0" {
+		t.Errorf("host should be %v, got %v", all[0], host)
+	}
+}